@@ -2,11 +2,11 @@
 package main
 
 import (
+	"container/list"
 	"fmt"
 	"math/rand"
 	"runtime"
 	"sync"
-	"time"
 )
 
 var _ = runtime.Compiler
@@ -28,15 +28,54 @@ func Cache(get <-chan struct {
 	// Cache
 	multiplicity := runtime.NumCPU()
 
-	const bytesLimit = 1048576
+	const sizeLimit = 1048576
 	type cacheEntry struct {
+		key  int
 		data []byte
-		last time.Time
-		sync.Mutex
+		size uint64
+		elem *list.Element
 	}
-	var mu sync.RWMutex
-	totalBytes := uint64(0)
+	// Hits change the eviction order, so all operations need the write lock.
+	var mu sync.Mutex
+	totalSize := uint64(0)
 	cache := make(map[int]*cacheEntry)
+	evictList := list.New()
+	// sizeOf returns the size of a value, for comparing with the limit.
+	sizeOf := func(in []byte) uint64 {
+		return uint64(len(in))
+	}
+
+	// touch updates the eviction order when e is used.
+	touch := func(e *cacheEntry) {
+		evictList.MoveToFront(e.elem)
+	}
+
+	// insert adds a new entry to the cache.
+	insert := func(key int, data []byte, size uint64) {
+		e := &cacheEntry{
+			key:  key,
+			data: data,
+			size: size,
+		}
+		e.elem = evictList.PushFront(e)
+
+		cache[key] = e
+		totalSize += size
+	}
+
+	// remove removes an entry from the cache, and returns its size.
+	remove := func(e *cacheEntry) uint64 {
+		evictList.Remove(e.elem)
+
+		delete(cache, e.key)
+		totalSize -= e.size
+		return e.size
+	}
+
+	// victim returns the next entry to evict. The cache must not be empty.
+	victim := func() *cacheEntry {
+		return evictList.Back().Value.(*cacheEntry)
+	}
 
 	defer func() {
 		close(hit)
@@ -56,15 +95,18 @@ func Cache(get <-chan struct {
 					if !open {
 						break handleLoop
 					}
-					mu.RLock()
+					mu.Lock()
 					e, ok := cache[g.Key]
-					mu.RUnlock()
 					if !ok {
+						mu.Unlock()
 						miss <- g
 
 						continue
 					}
-					e.Lock()
+					touch(e)
+					data := e.data
+
+					mu.Unlock()
 					hit <- struct {
 						Key  int
 						Ctx  struct{}
@@ -72,57 +114,26 @@ func Cache(get <-chan struct {
 					}{
 						Key:  g.Key,
 						Ctx:  g.Ctx,
-						Data: e.data,
+						Data: data,
 					}
-					e.last = time.Now()
-					e.Unlock()
 
 				case p, open := <-put:
 					if !open {
 						put = nil
 						continue
 					}
-					if len(p.Data) > bytesLimit {
+					size := sizeOf(p.Data)
+					if size > sizeLimit {
 						continue
 					}
-
-					// TODO: Can improve eviction algorithm - this is simplistic but O(n^2)
 					mu.Lock()
-					for {
-						// Find something to evict if needed.
-						var ek int
-						var ee *cacheEntry
-						et := time.Now()
-						for k, e := range cache {
-							e.Lock()
-							if e.last.Before(et) {
-								ee, et, ek = e, e.last, k
-							}
-							e.Unlock()
-						}
-						// Necessary to evict?
-						if totalBytes+uint64(len(p.Data)) > bytesLimit {
-							// Evict ek.
-							if ee == nil {
-								break
-							}
-							ee.Lock()
-							size := uint64(len(ee.data))
-							ee.Unlock()
-							totalBytes -= size
-							delete(cache, ek)
-							continue
-						}
-
-						// No - insert now.
-						size := uint64(len(p.Data))
-						cache[p.Key] = &cacheEntry{
-							data: p.Data,
-							last: time.Now(),
-						}
-						totalBytes += size
-						break
+					if old, ok := cache[p.Key]; ok {
+						remove(old)
+					}
+					for totalSize+size > sizeLimit {
+						remove(victim())
 					}
+					insert(p.Key, p.Data, size)
 					mu.Unlock()
 				}
 			}
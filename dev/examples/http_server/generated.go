@@ -3,6 +3,7 @@ package main
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"fmt"
 	"github.com/google/shenzhen-go/dev/parts"
@@ -68,7 +69,7 @@ var (
 			Namespace: "shenzhen_go",
 			Subsystem: "cache",
 			Name:      "size",
-			Help:      "Size of content in Cache nodes in bytes",
+			Help:      "Size of content in Cache nodes",
 		},
 		[]string{"node_name"},
 	)
@@ -77,7 +78,7 @@ var (
 			Namespace: "shenzhen_go",
 			Subsystem: "cache",
 			Name:      "limit",
-			Help:      "Upper limit of content size in Cache nodes in bytes",
+			Help:      "Upper limit of content size in Cache nodes",
 		},
 		[]string{"node_name"},
 	)
@@ -86,7 +87,7 @@ var (
 			Namespace: "shenzhen_go",
 			Subsystem: "cache",
 			Name:      "hits_size",
-			Help:      "Cumulative Cache node cache hits size in bytes",
+			Help:      "Cumulative Cache node cache hits size",
 		},
 		[]string{"node_name", "instance_num"},
 	)
@@ -95,7 +96,7 @@ var (
 			Namespace: "shenzhen_go",
 			Subsystem: "cache",
 			Name:      "puts_size",
-			Help:      "Cumulative Cache node cache insertions size in bytes",
+			Help:      "Cumulative Cache node cache insertions size",
 		},
 		[]string{"node_name", "instance_num"},
 	)
@@ -104,7 +105,25 @@ var (
 			Namespace: "shenzhen_go",
 			Subsystem: "cache",
 			Name:      "evictions_size",
-			Help:      "Cumulative Cache node cache evictions size in bytes",
+			Help:      "Cumulative Cache node cache evictions size",
+		},
+		[]string{"node_name", "instance_num"},
+	)
+	cacheExpirations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shenzhen_go",
+			Subsystem: "cache",
+			Name:      "expirations",
+			Help:      "Cache node cache expirations",
+		},
+		[]string{"node_name", "instance_num"},
+	)
+	cacheExpirationsSize = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shenzhen_go",
+			Subsystem: "cache",
+			Name:      "expirations_size",
+			Help:      "Cumulative Cache node cache expirations size",
 		},
 		[]string{"node_name", "instance_num"},
 	)
@@ -115,11 +134,14 @@ func init() {
 		cacheHits,
 		cacheMisses,
 		cachePuts,
+		cacheEvictions,
 		cacheSize,
 		cacheLimit,
 		cacheHitsSize,
 		cachePutsSize,
 		cacheEvictionsSize,
+		cacheExpirations,
+		cacheExpirationsSize,
 	)
 }
 
@@ -180,19 +202,64 @@ func Cache(get <-chan struct {
 	// Cache
 	multiplicity := runtime.NumCPU()
 
-	const bytesLimit = 1073741824
+	const sizeLimit = 1073741824
 	type cacheEntry struct {
+		key struct {
+			X, Y int
+			Z    uint
+		}
 		data []byte
-		last time.Time
-		sync.Mutex
+		size uint64
+		elem *list.Element
 	}
-	var mu sync.RWMutex
-	totalBytes := uint64(0)
+	// Hits change the eviction order, so all operations need the write lock.
+	var mu sync.Mutex
+	totalSize := uint64(0)
 	cache := make(map[struct {
 		X, Y int
 		Z    uint
 	}]*cacheEntry)
-	cacheLimit.With(prometheus.Labels{"node_name": "Cache"}).Set(bytesLimit)
+	evictList := list.New()
+	// sizeOf returns the size of a value, for comparing with the limit.
+	sizeOf := func(in []byte) uint64 {
+		return uint64(len(in))
+	}
+
+	// touch updates the eviction order when e is used.
+	touch := func(e *cacheEntry) {
+		evictList.MoveToFront(e.elem)
+	}
+
+	// insert adds a new entry to the cache.
+	insert := func(key struct {
+		X, Y int
+		Z    uint
+	}, data []byte, size uint64) {
+		e := &cacheEntry{
+			key:  key,
+			data: data,
+			size: size,
+		}
+		e.elem = evictList.PushFront(e)
+
+		cache[key] = e
+		totalSize += size
+	}
+
+	// remove removes an entry from the cache, and returns its size.
+	remove := func(e *cacheEntry) uint64 {
+		evictList.Remove(e.elem)
+
+		delete(cache, e.key)
+		totalSize -= e.size
+		return e.size
+	}
+
+	// victim returns the next entry to evict. The cache must not be empty.
+	victim := func() *cacheEntry {
+		return evictList.Back().Value.(*cacheEntry)
+	}
+	cacheLimit.With(prometheus.Labels{"node_name": "Cache"}).Set(sizeLimit)
 	cacheSize := cacheSize.With(prometheus.Labels{"node_name": "Cache"})
 	cacheSize.Set(0)
 
@@ -226,17 +293,20 @@ func Cache(get <-chan struct {
 					if !open {
 						break handleLoop
 					}
-					mu.RLock()
+					mu.Lock()
 					e, ok := cache[g.Key]
-					mu.RUnlock()
 					if !ok {
+						mu.Unlock()
 						miss <- g
 						cacheMisses.Inc()
 						continue
 					}
-					e.Lock()
+					touch(e)
+					data := e.data
+					size := e.size
+					mu.Unlock()
 					cacheHits.Inc()
-					cacheHitsSize.Add(float64(len(e.data)))
+					cacheHitsSize.Add(float64(size))
 					hit <- struct {
 						Key struct {
 							X, Y int
@@ -247,66 +317,32 @@ func Cache(get <-chan struct {
 					}{
 						Key:  g.Key,
 						Ctx:  g.Ctx,
-						Data: e.data,
+						Data: data,
 					}
-					e.last = time.Now()
-					e.Unlock()
 
 				case p, open := <-put:
 					if !open {
 						put = nil
 						continue
 					}
-					if len(p.Data) > bytesLimit {
+					size := sizeOf(p.Data)
+					if size > sizeLimit {
 						continue
 					}
-
-					// TODO: Can improve eviction algorithm - this is simplistic but O(n^2)
 					mu.Lock()
-					for {
-						// Find something to evict if needed.
-						var ek struct {
-							X, Y int
-							Z    uint
-						}
-						var ee *cacheEntry
-						et := time.Now()
-						for k, e := range cache {
-							e.Lock()
-							if e.last.Before(et) {
-								ee, et, ek = e, e.last, k
-							}
-							e.Unlock()
-						}
-						// Necessary to evict?
-						if totalBytes+uint64(len(p.Data)) > bytesLimit {
-							// Evict ek.
-							if ee == nil {
-								break
-							}
-							ee.Lock()
-							size := uint64(len(ee.data))
-							ee.Unlock()
-							totalBytes -= size
-							delete(cache, ek)
-							cacheEvictions.Inc()
-							cacheEvictionsSize.Add(float64(size))
-							continue
-						}
-
-						// No - insert now.
-						size := uint64(len(p.Data))
-						cache[p.Key] = &cacheEntry{
-							data: p.Data,
-							last: time.Now(),
-						}
-						totalBytes += size
-						cachePuts.Inc()
-						cachePutsSize.Add(float64(size))
-						cacheSize.Set(float64(totalBytes))
-						break
+					if old, ok := cache[p.Key]; ok {
+						remove(old)
+					}
+					for totalSize+size > sizeLimit {
+						esize := remove(victim())
+						cacheEvictions.Inc()
+						cacheEvictionsSize.Add(float64(esize))
 					}
+					insert(p.Key, p.Data, size)
+					cacheSize.Set(float64(totalSize))
 					mu.Unlock()
+					cachePuts.Inc()
+					cachePutsSize.Add(float64(size))
 				}
 			}
 		}()
@@ -338,8 +374,11 @@ func Duration(in <-chan *parts.HTTPRequest, out chan<- *parts.HTTPRequest) {
 		go func() {
 			defer multWG.Done()
 
-			h := promhttp.InstrumentHandlerDuration(sum, parts.HTTPHandler(out))
+			next := parts.HTTPHandler(out)
 			for r := range in {
+				h := promhttp.InstrumentHandlerDuration(sum, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					r.Forward(next, w)
+				}))
 				h.ServeHTTP(r.ResponseWriter, r.Request)
 				r.Close()
 			}
@@ -533,9 +572,16 @@ func HTTP_Server(errors chan<- error, manager <-chan parts.HTTPServerManager, re
 			Handler: parts.HTTPHandler(requests),
 			Addr:    mgr.Addr(),
 		}
+		serve := func() error {
+			l, err := parts.HTTPServerListen(mgr, "")
+			if err != nil {
+				return err
+			}
+			return svr.Serve(l)
+		}
 		done := make(chan struct{})
 		go func() {
-			if err := svr.ListenAndServe(); err != nil && errors != nil {
+			if err := serve(); err != nil && errors != nil {
 				errors <- err
 			}
 			close(done)
@@ -654,8 +700,11 @@ func Mandelbrot_duration(in <-chan *parts.HTTPRequest, out chan<- *parts.HTTPReq
 		go func() {
 			defer multWG.Done()
 
-			h := promhttp.InstrumentHandlerDuration(sum, parts.HTTPHandler(out))
+			next := parts.HTTPHandler(out)
 			for r := range in {
+				h := promhttp.InstrumentHandlerDuration(sum, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					r.Forward(next, w)
+				}))
 				h.ServeHTTP(r.ResponseWriter, r.Request)
 				r.Close()
 			}
@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const batchTypeParam = "$Any"
+
+var batchPins = pin.NewMap(
+	&pin.Definition{
+		Name:      "input",
+		Direction: pin.Input,
+		Type:      batchTypeParam,
+	},
+	&pin.Definition{
+		Name:      "output",
+		Direction: pin.Output,
+		Type:      "[]" + batchTypeParam,
+	})
+
+func init() {
+	model.RegisterPartType("Batch", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Batch{
+				MaxSize: 100,
+				MaxWait: time.Second,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Batch",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="batch-maxsize">Max batch size</label>
+					<input id="batch-maxsize" name="batch-maxsize" type="number" required title="Must be a whole number. 0 means no size limit." value="100"></input>
+				</div>
+				<div class="formfield">
+					<label for="batch-maxwait">Max wait</label>
+					<input id="batch-maxwait" name="batch-maxwait" type="text" required title="Must be a parseable time.Duration. 0s means no time limit." value="1s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Batch part consumes values of any type, and sends them on the
+				output grouped into slices. It is the inverse of Unbatch.
+			</p><p>
+				A batch is sent when it reaches the maximum batch size, or when the
+				maximum wait has elapsed since the first value in the batch was read,
+				whichever happens first. A maximum of 0 disables that limit.
+				Empty batches are never sent.
+			</p><p>
+				When the input is closed, any partial batch is sent, and then the
+				output is closed.
+			</p><p>
+				Multiplicity affects how many goroutines perform batching.
+				Each instance collects its own batches independently.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// Batch is a part which consumes values of any type, and sends them
+// on the output in slices of bounded size, after a bounded wait, or both.
+type Batch struct {
+	MaxSize uint          `json:"max_size"`
+	MaxWait time.Duration `json:"max_wait,omitempty"`
+}
+
+// Clone returns a clone of this Batch.
+func (b *Batch) Clone() model.Part {
+	b0 := *b
+	return &b0
+}
+
+// Impl returns the Batch implementation.
+func (b *Batch) Impl(n *model.Node) model.PartImpl {
+	var imps []string
+	hb, bb := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if b.MaxSize != 0 {
+		fmt.Fprintf(hb, "const maxSize = %d\n", b.MaxSize)
+	}
+	if b.MaxWait != 0 {
+		fmt.Fprintf(hb, "const maxWait = %d // %v\n", b.MaxWait, b.MaxWait)
+		imps = append(imps, `"time"`)
+	}
+
+	fmt.Fprintf(bb, "var batch []%s\n", n.TypeParams[batchTypeParam])
+	if b.MaxSize == 0 && b.MaxWait == 0 {
+		// No limits: one batch of everything.
+		bb.WriteString(`for in := range input {
+			batch = append(batch, in)
+		}
+		if len(batch) > 0 {
+			output <- batch
+		}`)
+		return model.PartImpl{
+			Body: bb.String(),
+			Tail: "close(output)",
+		}
+	}
+	if b.MaxWait != 0 {
+		bb.WriteString(`var timer *time.Timer
+		var timeout <-chan time.Time
+		`)
+	}
+	bb.WriteString(`batchLoop:
+	for {
+		select {
+		case in, open := <-input:
+			if !open {
+				break batchLoop
+			}
+			batch = append(batch, in)
+		`)
+	if b.MaxWait != 0 {
+		bb.WriteString(`if len(batch) == 1 {
+				timer = time.NewTimer(maxWait)
+				timeout = timer.C
+			}
+		`)
+	}
+	if b.MaxSize != 0 {
+		bb.WriteString(`if len(batch) < maxSize {
+				continue
+			}
+		`)
+	} else {
+		bb.WriteString("continue\n")
+	}
+	if b.MaxWait != 0 {
+		bb.WriteString(`case <-timeout:
+		`)
+	}
+	bb.WriteString("}\n")
+	if b.MaxWait != 0 {
+		bb.WriteString(`timer.Stop()
+		timeout = nil
+		`)
+	}
+	bb.WriteString(`output <- batch
+		batch = nil
+	}
+	if len(batch) > 0 {
+		output <- batch
+	}`)
+
+	return model.PartImpl{
+		Imports: imps,
+		Head:    hb.String(),
+		Body:    bb.String(),
+		Tail:    "close(output)",
+	}
+}
+
+// Pins returns a map declaring a single input of any type
+// and a single output of slices of the input type.
+func (b *Batch) Pins() pin.Map { return batchPins }
+
+// TypeKey returns "Batch".
+func (b *Batch) TypeKey() string { return "Batch" }
@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	inputBatchMaxSize = doc.ElementByID("batch-maxsize")
+	inputBatchMaxWait = doc.ElementByID("batch-maxwait")
+
+	focusedBatch *Batch
+)
+
+func init() {
+	inputBatchMaxSize.AddEventListener("change", func(dom.Object) {
+		focusedBatch.MaxSize = uint(inputBatchMaxSize.Get("value").Int())
+	})
+	inputBatchMaxWait.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedBatch.MaxWait = t
+	}))
+}
+
+func (b *Batch) GainFocus() {
+	focusedBatch = b
+	inputBatchMaxSize.Set("value", b.MaxSize)
+	inputBatchMaxWait.Set("value", b.MaxWait.String())
+}
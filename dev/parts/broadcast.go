@@ -30,6 +30,8 @@ func init() {
 				Name: "Broadcast",
 				Editor: `<div class="form"><div class="formfield">
 					<label>Number of outputs: <input id="broadcast-outputnum" type="number"></input></label>
+				</div><div class="formfield">
+					<label>Delivery: <input id="broadcast-delivery" type="text" title="Comma-separated list of blocking, drop, or buffered N"></input></label>
 				</div></div>`,
 			},
 			{
@@ -37,42 +39,121 @@ func init() {
 				Editor: `<div>
 			<p>A Broadcast part copies every input value to all of the outputs. 
 			The number of outputs is configurable.</p>
+			<p>How values are delivered to each output is configurable with a
+			comma-separated list, with one entry for each output in order
+			(for example, <code>blocking, drop, buffered 100</code>).
+			Outputs without an entry are blocking.</p>
+			<ul>
+			<li><code>blocking</code>: the part waits for the output to receive each value
+			before continuing, so a slow consumer slows down every output.</li>
+			<li><code>drop</code>: if the output isn't ready to receive a value, the value
+			is sent to the drops output instead (without waiting), or discarded.</li>
+			<li><code>buffered N</code>: values wait in a queue of size N for the output
+			to receive them. The part only waits if the queue is full.</li>
+			</ul>
 			</div>`,
 			},
 		},
 	})
 }
 
+// BroadcastMode is how a Broadcast delivers values to an output.
+type BroadcastMode string
+
+// Valid values of BroadcastMode.
+const (
+	BroadcastBlocking BroadcastMode = "blocking"
+	BroadcastDrop     BroadcastMode = "drop"
+	BroadcastBuffered BroadcastMode = "buffered"
+)
+
+// BroadcastDelivery configures delivery to one output of a Broadcast.
+type BroadcastDelivery struct {
+	Mode       BroadcastMode `json:"mode"`
+	BufferSize uint          `json:"buffer_size,omitempty"`
+}
+
 // Broadcast is a part that repeats a copy of each input messge to a
 // configurable number of ouptuts.
 type Broadcast struct {
 	OutputNum uint `json:"output_num"`
+
+	// Delivery configures each output by index. Outputs beyond the end
+	// of Delivery are blocking.
+	Delivery []BroadcastDelivery `json:"delivery,omitempty"`
 }
 
 // Clone returns a clone of this part.
-func (b Broadcast) Clone() model.Part { return b }
+func (b Broadcast) Clone() model.Part {
+	b.Delivery = append([]BroadcastDelivery(nil), b.Delivery...)
+	return b
+}
+
+func (b Broadcast) delivery(i uint) BroadcastDelivery {
+	if i < uint(len(b.Delivery)) {
+		return b.Delivery[i]
+	}
+	return BroadcastDelivery{Mode: BroadcastBlocking}
+}
+
+func (b Broadcast) anyDrop() bool {
+	for i := uint(0); i < b.OutputNum; i++ {
+		if b.delivery(i).Mode == BroadcastDrop {
+			return true
+		}
+	}
+	return false
+}
 
 // Impl returns the implementation.
 func (b Broadcast) Impl(n *model.Node) model.PartImpl {
-	bb, tb := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
-	bb.WriteString("for in := range input {\n")
+	hasDrops := b.anyDrop() && n.Connections["drops"] != "nil"
+	hb, bb, eb, tb := bytes.NewBuffer(nil), bytes.NewBuffer(nil), bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	for i := uint(0); i < b.OutputNum; i++ {
 		o := fmt.Sprintf("output%d", i)
 		if n.Connections[o] == "nil" {
 			// We know at design time whether a pin is nil.
 			continue
 		}
-		fmt.Fprintf(bb, "\t%s <- in\n", o)
 		fmt.Fprintf(tb, "close(%s)\n", o)
+		switch d := b.delivery(i); d.Mode {
+		case BroadcastBlocking:
+			fmt.Fprintf(bb, "\t%s <- in\n", o)
+		case BroadcastDrop:
+			fmt.Fprintf(bb, "\tselect {\n\tcase %s <- in:\n\tdefault:\n", o)
+			if hasDrops {
+				bb.WriteString("\t\tselect {\n\t\tcase drops <- in:\n\t\tdefault:\n\t\t}\n")
+			}
+			bb.WriteString("\t}\n")
+		case BroadcastBuffered:
+			// Each buffered output has a goroutine forwarding from the buffer.
+			fmt.Fprintf(hb, "buf%d := make(chan %s, %d)\n", i, n.TypeParams["$Any"], d.BufferSize)
+			fmt.Fprintf(hb, "done%d := make(chan struct{})\n", i)
+			fmt.Fprintf(hb, "go func() {\n\tfor v := range buf%d {\n\t\t%s <- v\n\t}\n\tclose(done%d)\n}()\n", i, o, i)
+			fmt.Fprintf(bb, "\tbuf%d <- in\n", i)
+			fmt.Fprintf(eb, "close(buf%d)\n<-done%d\n", i, i)
+		default:
+			panic("unknown mode " + d.Mode)
+		}
+	}
+	if hasDrops {
+		tb.WriteString("close(drops)\n")
+	}
+	hb.WriteString("for in := range input {\n")
+	hb.Write(bb.Bytes())
+	hb.WriteString("}")
+	if eb.Len() > 0 {
+		hb.WriteString("\n")
+		hb.Write(eb.Bytes())
 	}
-	bb.WriteString("}")
 	return model.PartImpl{
-		Body: bb.String(),
+		Body: hb.String(),
 		Tail: tb.String(),
 	}
 }
 
-// Pins returns a map with one input and N outputs.
+// Pins returns a map with one input and N outputs, and an output for
+// dropped values if any output is in drop mode.
 func (b Broadcast) Pins() pin.Map {
 	m := pin.NewMap(&pin.Definition{
 		Name:      "input",
@@ -87,6 +168,13 @@ func (b Broadcast) Pins() pin.Map {
 			Type:      "$Any",
 		}
 	}
+	if b.anyDrop() {
+		m["drops"] = &pin.Definition{
+			Name:      "drops",
+			Direction: pin.Output,
+			Type:      "$Any",
+		}
+	}
 	return m
 }
 
@@ -16,20 +16,78 @@
 
 package parts
 
-import "github.com/google/shenzhen-go/dev/dom"
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
 
 var (
 	inputBroadcastOutputNum = doc.ElementByID("broadcast-outputnum")
+	inputBroadcastDelivery  = doc.ElementByID("broadcast-delivery")
 	focusedBroadcast        *Broadcast
 )
 
+// parseBroadcastDelivery parses a comma-separated list of delivery modes,
+// such as "blocking, drop, buffered 10".
+func parseBroadcastDelivery(s string) ([]BroadcastDelivery, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var ds []BroadcastDelivery
+	for _, e := range strings.Split(s, ",") {
+		f := strings.Fields(e)
+		if len(f) == 0 {
+			return nil, fmt.Errorf("empty delivery mode")
+		}
+		d := BroadcastDelivery{Mode: BroadcastMode(f[0])}
+		switch {
+		case (d.Mode == BroadcastBlocking || d.Mode == BroadcastDrop) && len(f) == 1:
+		case d.Mode == BroadcastBuffered && len(f) == 2:
+			n, err := strconv.ParseUint(f[1], 10, 0)
+			if err != nil {
+				return nil, err
+			}
+			d.BufferSize = uint(n)
+		default:
+			return nil, fmt.Errorf("invalid delivery mode %q", e)
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+func formatBroadcastDelivery(ds []BroadcastDelivery) string {
+	ss := make([]string, 0, len(ds))
+	for _, d := range ds {
+		if d.Mode == BroadcastBuffered {
+			ss = append(ss, fmt.Sprintf("%s %d", d.Mode, d.BufferSize))
+			continue
+		}
+		ss = append(ss, string(d.Mode))
+	}
+	return strings.Join(ss, ", ")
+}
+
 func init() {
 	inputBroadcastOutputNum.AddEventListener("change", func(dom.Object) {
 		focusedBroadcast.OutputNum = uint(inputBroadcastOutputNum.Get("value").Int())
 	})
+	inputBroadcastDelivery.AddEventListener("change", func(dom.Object) {
+		ds, err := parseBroadcastDelivery(inputBroadcastDelivery.Get("value").String())
+		if err != nil {
+			log.Printf("couldn't parse delivery: %v", err)
+			return
+		}
+		focusedBroadcast.Delivery = ds
+	})
 }
 
 func (b *Broadcast) GainFocus() {
 	focusedBroadcast = b
 	inputBroadcastOutputNum.Set("value", b.OutputNum)
+	inputBroadcastDelivery.Set("value", formatBroadcastDelivery(b.Delivery))
 }
@@ -17,27 +17,30 @@ package parts
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/google/shenzhen-go/dev/model"
 	"github.com/google/shenzhen-go/dev/model/pin"
 )
 
 const (
-	cacheKeyTypeParam = "$Key"
-	cacheCtxTypeParam = "$Ctx"
+	cacheKeyTypeParam   = "$Key"
+	cacheCtxTypeParam   = "$Ctx"
+	cacheValueTypeParam = "$Value"
 )
 
 func cacheGetType(kt, ct string) string {
 	return fmt.Sprintf("struct{ Key %s; Ctx %s }", kt, ct)
 }
 
-func cacheHitType(kt, ct string) string {
-	return fmt.Sprintf("struct{ Key %s; Ctx %s; Data []byte }", kt, ct)
+func cacheHitType(kt, ct, vt string) string {
+	return fmt.Sprintf("struct{ Key %s; Ctx %s; Data %s }", kt, ct, vt)
 }
 
-func cachePutType(kt string) string {
-	return fmt.Sprintf("struct{ Key %s; Data []byte }", kt)
+func cachePutType(kt, vt string) string {
+	return fmt.Sprintf("struct{ Key %s; Data %s }", kt, vt)
 }
 
 var (
@@ -50,12 +53,12 @@ var (
 		&pin.Definition{
 			Name:      "put",
 			Direction: pin.Input,
-			Type:      cachePutType(cacheKeyTypeParam),
+			Type:      cachePutType(cacheKeyTypeParam, cacheValueTypeParam),
 		},
 		&pin.Definition{
 			Name:      "hit",
 			Direction: pin.Output,
-			Type:      cacheHitType(cacheKeyTypeParam, cacheCtxTypeParam),
+			Type:      cacheHitType(cacheKeyTypeParam, cacheCtxTypeParam, cacheValueTypeParam),
 		},
 		&pin.Definition{
 			Name:      "miss",
@@ -64,18 +67,115 @@ var (
 		},
 	)
 
+	// Entries are kept in an eviction order: a list from most to least
+	// recently used, or in LFU mode a heap from least to most frequently
+	// used. With a TTL, entries are also kept in a list in order of expiry.
 	cacheHeadTmpl = template.Must(template.New("cache-head").Parse(`
-	const bytesLimit = {{.BytesLimit}}
+	const sizeLimit = {{.SizeLimit}}
+	{{if .TTL -}}
+	const ttl = {{.TTLNanos}} // {{.TTLString}}
+	{{end -}}
 	type cacheEntry struct {
-		data []byte
-		last time.Time
-		{{if .Mult}}sync.Mutex{{end}}
+		key  {{.KeyType}}
+		data {{.ValueType}}
+		size uint64
+		{{if .LFU -}}
+		hits  uint64
+		used  uint64
+		index int
+		{{- else -}}
+		elem *list.Element
+		{{- end}}
+		{{if .TTL -}}
+		expires time.Time
+		expElem *list.Element
+		{{- end}}
 	}
-	{{if .Mult}}var mu sync.RWMutex{{end}}
-	totalBytes := uint64(0)
+	{{if .Mult -}}
+	// Hits change the eviction order, so all operations need the write lock.
+	var mu sync.Mutex
+	{{end -}}
+	totalSize := uint64(0)
 	cache := make(map[{{.KeyType}}]*cacheEntry)
+	{{if .LFU -}}
+	useCount := uint64(0)
+	evictHeap := &parts.Heap{
+		LessFunc: func(a, b interface{}) bool {
+			x, y := a.(*cacheEntry), b.(*cacheEntry)
+			if x.hits != y.hits {
+				return x.hits < y.hits
+			}
+			return x.used < y.used
+		},
+		IndexFunc: func(x interface{}, i int) { x.(*cacheEntry).index = i },
+	}
+	{{else -}}
+	evictList := list.New()
+	{{end -}}
+	{{if .TTL -}}
+	expiryList := list.New()
+	{{end -}}
+
+	// sizeOf returns the size of a value, for comparing with the limit.
+	sizeOf := func(in {{.ValueType}}) uint64 {
+		return uint64({{.SizeFunc}})
+	}
+
+	// touch updates the eviction order when e is used.
+	touch := func(e *cacheEntry) {
+		{{if .LFU -}}
+		e.hits++
+		useCount++
+		e.used = useCount
+		heap.Fix(evictHeap, e.index)
+		{{- else -}}
+		evictList.MoveToFront(e.elem)
+		{{- end}}
+	}
+
+	// insert adds a new entry to the cache.
+	insert := func(key {{.KeyType}}, data {{.ValueType}}, size uint64) {
+		e := &cacheEntry{
+			key:  key,
+			data: data,
+			size: size,
+			{{if .TTL}}expires: time.Now().Add(ttl),{{end}}
+		}
+		{{if .LFU -}}
+		useCount++
+		e.used = useCount
+		heap.Push(evictHeap, e)
+		{{- else -}}
+		e.elem = evictList.PushFront(e)
+		{{- end}}
+		{{if .TTL}}e.expElem = expiryList.PushBack(e){{end}}
+		cache[key] = e
+		totalSize += size
+	}
+
+	// remove removes an entry from the cache, and returns its size.
+	remove := func(e *cacheEntry) uint64 {
+		{{if .LFU -}}
+		heap.Remove(evictHeap, e.index)
+		{{- else -}}
+		evictList.Remove(e.elem)
+		{{- end}}
+		{{if .TTL}}expiryList.Remove(e.expElem){{end}}
+		delete(cache, e.key)
+		totalSize -= e.size
+		return e.size
+	}
+
+	// victim returns the next entry to evict. The cache must not be empty.
+	victim := func() *cacheEntry {
+		{{if .LFU -}}
+		return evictHeap.Items[0].(*cacheEntry)
+		{{- else -}}
+		return evictList.{{.VictimEnd}}().Value.(*cacheEntry)
+		{{- end}}
+	}
 	{{if .Prometheus -}}
-	cacheLimit.With(prometheus.Labels{"node_name":"{{.NodeName}}"}).Set(bytesLimit)
+	cacheLimit.With(prometheus.Labels{"node_name":"{{.NodeName}}"}).Set(sizeLimit)
 	cacheSize := cacheSize.With(prometheus.Labels{"node_name":"{{.NodeName}}"})
 	cacheSize.Set(0)
 	{{end -}}`))
@@ -93,6 +193,20 @@ var (
 	cacheHitsSize := cacheHitsSize.With(labels)
 	cachePutsSize := cachePutsSize.With(labels)
 	cacheEvictionsSize := cacheEvictionsSize.With(labels)
+	{{if .TTL -}}
+	cacheExpirations := cacheExpirations.With(labels)
+	cacheExpirationsSize := cacheExpirationsSize.With(labels)
+	{{end -}}
+	{{end -}}
+	{{if .TTL -}}
+	// Expired entries are removed when they are found by a get, and
+	// periodically by instance 0.
+	var sweep <-chan time.Time
+	if instanceNumber == 0 {
+		ticker := time.NewTicker(ttl)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
 	{{end -}}
 handleLoop:
 	for {
@@ -101,83 +215,83 @@ handleLoop:
 			if !open {
 				break handleLoop
 			}
-			{{if .Mult}}mu.RLock(){{end}}
+			{{if .Mult}}mu.Lock(){{end}}
 			e, ok := cache[g.Key]
-			{{if .Mult}}mu.RUnlock(){{end}}
+			{{if .TTL -}}
+			if ok && !time.Now().Before(e.expires) {
+				{{if .Prometheus}}size := {{end}}remove(e)
+				{{if .Prometheus -}}
+				cacheExpirations.Inc()
+				cacheExpirationsSize.Add(float64(size))
+				cacheSize.Set(float64(totalSize))
+				{{end -}}
+				ok = false
+			}
+			{{end -}}
 			if !ok {
+				{{if .Mult}}mu.Unlock(){{end}}
 				miss <- g
 				{{if .Prometheus}}cacheMisses.Inc(){{end}}
 				continue
 			}
-			{{if .Mult}}e.Lock(){{end}}
+			touch(e)
+			data := e.data
+			{{if .Prometheus}}size := e.size{{end}}
+			{{if .Mult}}mu.Unlock(){{end}}
 			{{if .Prometheus -}}
 			cacheHits.Inc()
-			cacheHitsSize.Add(float64(len(e.data)))
+			cacheHitsSize.Add(float64(size))
 			{{end -}}
 			hit <- {{.HitType}}{
 				Key: g.Key,
 				Ctx: g.Ctx,
-				Data: e.data,
+				Data: data,
 			}
-			e.last = time.Now()
-			{{if .Mult}}e.Unlock(){{end}}
-			
+
 		case p, open := <-put:
 			if !open {
 				put = nil
 				continue
 			}
-			if len(p.Data) > bytesLimit {
+			size := sizeOf(p.Data)
+			if size > sizeLimit {
 				continue
 			}
-			
-			// TODO: Can improve eviction algorithm - this is simplistic but O(n^2)
 			{{if .Mult}}mu.Lock(){{end}}
-			for {
-				// Find something to evict if needed.
-				var ek {{.KeyType}}
-				var ee *cacheEntry
-				et := {{.InitTime}}
-				for k, e := range cache {
-					{{if .Mult}}e.Lock(){{end}}
-					if e.last.{{.TimeComp}}(et) {
-						ee, et, ek = e, e.last, k
-					}
-					{{if .Mult}}e.Unlock(){{end}}
-				}
-				// Necessary to evict?
-				if totalBytes + uint64(len(p.Data)) > bytesLimit {
-					// Evict ek.
-					if ee == nil {
-						break
-					}
-					{{if .Mult}}ee.Lock(){{end}}
-					size := uint64(len(ee.data))
-					{{if .Mult}}ee.Unlock(){{end}}
-					totalBytes -= size
-					delete(cache, ek)
-					{{if .Prometheus -}}
-					cacheEvictions.Inc()
-					cacheEvictionsSize.Add(float64(size))
-					{{end -}}
-					continue
-				}
-
-				// No - insert now.
-				size := uint64(len(p.Data))
-				cache[p.Key] = &cacheEntry{
-					data: p.Data,
-					last: time.Now(),
-				}
-				totalBytes += size
+			if old, ok := cache[p.Key]; ok {
+				remove(old)
+			}
+			for totalSize+size > sizeLimit {
+				{{if .Prometheus}}esize := {{end}}remove(victim())
 				{{if .Prometheus -}}
-				cachePuts.Inc()
-				cachePutsSize.Add(float64(size))
-				cacheSize.Set(float64(totalBytes))
+				cacheEvictions.Inc()
+				cacheEvictionsSize.Add(float64(esize))
 				{{end -}}
-				break
 			}
+			insert(p.Key, p.Data, size)
+			{{if .Prometheus -}}
+			cacheSize.Set(float64(totalSize))
+			{{end -}}
 			{{if .Mult}}mu.Unlock(){{end}}
+			{{if .Prometheus -}}
+			cachePuts.Inc()
+			cachePutsSize.Add(float64(size))
+			{{end -}}
+		{{if .TTL}}
+		case now := <-sweep:
+			{{if .Mult}}mu.Lock(){{end}}
+			for f := expiryList.Front(); f != nil && !now.Before(f.Value.(*cacheEntry).expires); f = expiryList.Front() {
+				{{if .Prometheus}}size := {{end}}remove(f.Value.(*cacheEntry))
+				{{if .Prometheus -}}
+				cacheExpirations.Inc()
+				cacheExpirationsSize.Add(float64(size))
+				{{end -}}
+			}
+			{{if .Prometheus -}}
+			cacheSize.Set(float64(totalSize))
+			{{end -}}
+			{{if .Mult}}mu.Unlock(){{end}}
+		{{end -}}
 		}
 	}`))
 )
@@ -233,7 +347,7 @@ func init() {
 					Namespace: "shenzhen_go",
 					Subsystem: "cache",
 					Name:      "size",
-					Help:      "Size of content in Cache nodes in bytes",
+					Help:      "Size of content in Cache nodes",
 				},
 				[]string{"node_name"},
 			)
@@ -242,7 +356,7 @@ func init() {
 					Namespace: "shenzhen_go",
 					Subsystem: "cache",
 					Name:      "limit",
-					Help:      "Upper limit of content size in Cache nodes in bytes",
+					Help:      "Upper limit of content size in Cache nodes",
 				},
 				[]string{"node_name"},
 			)
@@ -251,7 +365,7 @@ func init() {
 					Namespace: "shenzhen_go",
 					Subsystem: "cache",
 					Name:      "hits_size",
-					Help:      "Cumulative Cache node cache hits size in bytes",
+					Help:      "Cumulative Cache node cache hits size",
 				},
 				[]string{"node_name", "instance_num"},
 			)
@@ -260,7 +374,7 @@ func init() {
 					Namespace: "shenzhen_go",
 					Subsystem: "cache",
 					Name:      "puts_size",
-					Help:      "Cumulative Cache node cache insertions size in bytes",
+					Help:      "Cumulative Cache node cache insertions size",
 				},
 				[]string{"node_name", "instance_num"},
 			)
@@ -269,7 +383,25 @@ func init() {
 					Namespace: "shenzhen_go",
 					Subsystem: "cache",
 					Name:      "evictions_size",
-					Help:      "Cumulative Cache node cache evictions size in bytes",
+					Help:      "Cumulative Cache node cache evictions size",
+				},
+				[]string{"node_name", "instance_num"},
+			)
+			cacheExpirations = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Namespace: "shenzhen_go",
+					Subsystem: "cache",
+					Name:      "expirations",
+					Help:      "Cache node cache expirations",
+				},
+				[]string{"node_name", "instance_num"},
+			)
+			cacheExpirationsSize = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Namespace: "shenzhen_go",
+					Subsystem: "cache",
+					Name:      "expirations_size",
+					Help:      "Cumulative Cache node cache expirations size",
 				},
 				[]string{"node_name", "instance_num"},
 			)
@@ -280,11 +412,14 @@ func init() {
 				cacheHits, 
 				cacheMisses, 
 				cachePuts, 
+				cacheEvictions,
 				cacheSize, 
 				cacheLimit, 
 				cacheHitsSize, 
 				cachePutsSize, 
 				cacheEvictionsSize,
+				cacheExpirations,
+				cacheExpirationsSize,
 			)
 		}
 		`,
@@ -298,16 +433,25 @@ func init() {
 						<label for="cache-enableprometheus">Enable Prometheus metrics</label>
 					</div>
 					<div class="formfield">
-						<label for="cache-contentbyteslimit">Maximum bytes</label>
+						<label for="cache-contentbyteslimit">Maximum size</label>
 						<input id="cache-contentbyteslimit" name="cache-contentbyteslimit" type="number" required title="Must be a whole number, at least 1." value="1073741824"></input>
 					</div>
+					<div class="formfield">
+						<label for="cache-sizefunc">Size expression</label>
+						<input id="cache-sizefunc" name="cache-sizefunc" type="text" title="Must be a Go expression using in, or blank"></input>
+					</div>
 					<div class="formfield">
 						<label for="cache-evictionmode">Eviction mode</label>
 						<select id="cache-evictionmode" name="cache-evictionmode">
 							<option value="lru" selected>LRU (least recently used)</option>
 							<option value="mru">MRU (most recently used)</option>
+							<option value="lfu">LFU (least frequently used)</option>
 						</select>
 					</div>
+					<div class="formfield">
+						<label for="cache-ttl">TTL (0 for no expiry)</label>
+						<input id="cache-ttl" name="cache-ttl" type="text" required title="Must be a parseable time.Duration" value="0s"></input>
+					</div>
 				</div>`,
 			},
 			{
@@ -315,7 +459,21 @@ func init() {
 				Editor: `<div><p>
 				A Cache part caches content in memory. It supports concurrently inserting and retrieving items.
 			</p><p>
-				TODO: Implement Cache part.
+				Content sent to put is stored under its key. For each value received on get,
+				the cached content for the key is sent to hit, or if there is none, the value
+				is sent to miss. The Ctx field is passed through unchanged.
+			</p><p>
+				The size of each value is given by the size expression, a Go expression in terms
+				of the value <code>in</code>. If it is blank, the size is <code>len(in)</code> for
+				slices and strings, and 1 for other types (so the maximum size is a maximum
+				number of entries).
+			</p><p>
+				When storing content would exceed the maximum size, other content is evicted
+				first. LRU mode evicts the least recently used content, MRU the most recently
+				used, and LFU the least frequently used (and of those, the least recently used).
+			</p><p>
+				If the TTL is not zero, content expires the TTL after it is put. Expired content
+				is never sent to hit, and is removed periodically.
 			</p></div>`,
 			},
 		},
@@ -324,9 +482,13 @@ func init() {
 
 // Cache is a part which caches content in memory.
 type Cache struct {
+	// ContentBytesLimit is measured by SizeFunc, which is bytes by default
+	// for []byte values.
 	ContentBytesLimit uint64            `json:"content_bytes_limit"`
 	EnablePrometheus  bool              `json:"enable_prometheus"`
 	EvictionMode      CacheEvictionMode `json:"eviction_mode"`
+	TTL               time.Duration     `json:"ttl,omitempty"`
+	SizeFunc          string            `json:"size_func,omitempty"`
 }
 
 // CacheEvictionMode is how the cache decides which content to evict
@@ -337,14 +499,19 @@ type CacheEvictionMode string
 const (
 	EvictLRU CacheEvictionMode = "lru" // Least recently used
 	EvictMRU CacheEvictionMode = "mru" // Most recently used
+	EvictLFU CacheEvictionMode = "lfu" // Least frequently used
 )
 
-func (m CacheEvictionMode) searchParams() (init, comp string) {
+// victimEnd returns the end of the eviction list to evict from, or ""
+// for LFU mode, which uses a heap instead.
+func (m CacheEvictionMode) victimEnd() string {
 	switch m {
 	case EvictLRU:
-		return "time.Now()", "Before"
+		return "Back"
 	case EvictMRU:
-		return "time.Time{}", "After"
+		return "Front"
+	case EvictLFU:
+		return ""
 	default:
 		panic("unrecognised EvictionMode " + m)
 	}
@@ -359,19 +526,33 @@ func (c *Cache) Clone() model.Part {
 // Impl returns a cache implementation.
 func (c *Cache) Impl(n *model.Node) model.PartImpl {
 	params := struct {
-		BytesLimit                           uint64
-		KeyType, HitType, InitTime, TimeComp string
-		Mult, Prometheus                     bool
-		NodeName                             string
+		SizeLimit                   uint64
+		KeyType, ValueType, HitType string
+		SizeFunc, VictimEnd         string
+		Mult, Prometheus, LFU, TTL  bool
+		TTLNanos                    int64
+		TTLString, NodeName         string
 	}{
-		BytesLimit: c.ContentBytesLimit,
+		SizeLimit:  c.ContentBytesLimit,
 		KeyType:    n.TypeParams[cacheKeyTypeParam].String(),
+		ValueType:  n.TypeParams[cacheValueTypeParam].String(),
+		SizeFunc:   c.SizeFunc,
+		VictimEnd:  c.EvictionMode.victimEnd(),
 		Mult:       n.Multiplicity != "1",
+		LFU:        c.EvictionMode == EvictLFU,
+		TTL:        c.TTL > 0,
+		TTLNanos:   int64(c.TTL),
+		TTLString:  c.TTL.String(),
 		NodeName:   n.Name,
 		Prometheus: c.EnablePrometheus,
 	}
-	params.HitType = cacheHitType(params.KeyType, n.TypeParams[cacheCtxTypeParam].String())
-	params.InitTime, params.TimeComp = c.EvictionMode.searchParams()
+	params.HitType = cacheHitType(params.KeyType, n.TypeParams[cacheCtxTypeParam].String(), params.ValueType)
+	if params.SizeFunc == "" {
+		params.SizeFunc = "1"
+		if strings.HasPrefix(params.ValueType, "[]") || params.ValueType == "string" {
+			params.SizeFunc = "len(in)"
+		}
+	}
 	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	if err := cacheHeadTmpl.Execute(h, params); err != nil {
 		panic("couldn't execute cache-head template: " + err.Error())
@@ -379,7 +560,19 @@ func (c *Cache) Impl(n *model.Node) model.PartImpl {
 	if err := cacheBodyTmpl.Execute(b, params); err != nil {
 		panic("couldn't execute cache-body template: " + err.Error())
 	}
-	imps := []string{`"time"`}
+	var imps []string
+	if params.LFU {
+		imps = append(imps,
+			`"container/heap"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		)
+	}
+	if !params.LFU || params.TTL {
+		imps = append(imps, `"container/list"`)
+	}
+	if params.TTL {
+		imps = append(imps, `"time"`)
+	}
 	if params.Mult {
 		imps = append(imps, `"sync"`)
 	}
@@ -16,12 +16,18 @@
 
 package parts
 
-import "github.com/google/shenzhen-go/dev/dom"
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
 
 var (
 	inputCacheContentBytesLimit = doc.ElementByID("cache-contentbyteslimit")
 	inputCacheEnablePrometheus  = doc.ElementByID("cache-enableprometheus")
 	selectCacheEvictionMode     = doc.ElementByID("cache-evictionmode")
+	inputCacheTTL               = doc.ElementByID("cache-ttl")
+	inputCacheSizeFunc          = doc.ElementByID("cache-sizefunc")
 
 	focusedCache *Cache
 )
@@ -36,6 +42,12 @@ func init() {
 	selectCacheEvictionMode.AddEventListener("change", func(dom.Object) {
 		focusedCache.EvictionMode = CacheEvictionMode(selectCacheEvictionMode.Get("value").String())
 	})
+	inputCacheTTL.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedCache.TTL = t
+	}))
+	inputCacheSizeFunc.AddEventListener("change", func(dom.Object) {
+		focusedCache.SizeFunc = inputCacheSizeFunc.Get("value").String()
+	})
 }
 
 func (c *Cache) GainFocus() {
@@ -43,4 +55,6 @@ func (c *Cache) GainFocus() {
 	inputCacheContentBytesLimit.Set("value", c.ContentBytesLimit)
 	inputCacheEnablePrometheus.Set("checked", c.EnablePrometheus)
 	selectCacheEvictionMode.Set("value", c.EvictionMode)
+	inputCacheTTL.Set("value", c.TTL.String())
+	inputCacheSizeFunc.Set("value", c.SizeFunc)
 }
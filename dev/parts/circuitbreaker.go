@@ -0,0 +1,324 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	circuitBreakerHeadTmpl = template.Must(template.New("circuitbreaker-head").Parse(`
+	breaker := &parts.Breaker{
+		Window:       {{.Window}},
+		MinRequests:  {{.MinRequests}},
+		Threshold:    {{.Threshold}},
+		OpenDuration: {{.OpenDurationNanos}}, // {{.OpenDuration}}
+		Probes:       {{.Probes}},
+	}
+	{{if .Prometheus -}}
+	stateGauge := circuitBreakerState.With(prometheus.Labels{"node_name": "{{.NodeName}}"})
+	stateGauge.Set(float64(parts.CircuitClosed))
+	transitions := circuitBreakerTransitions.MustCurryWith(prometheus.Labels{"node_name": "{{.NodeName}}"})
+	breaker.OnStateChange = func(from, to parts.CircuitState) {
+		stateGauge.Set(float64(to))
+		transitions.With(prometheus.Labels{"from": from.String(), "to": to.String()}).Inc()
+	}
+	{{end -}}`))
+
+	circuitBreakerBodyTmpl = template.Must(template.New("circuitbreaker-body").Parse(`
+	{{if .Prometheus -}}
+	rejections := circuitBreakerRejections.With(prometheus.Labels{
+		"node_name": "{{.NodeName}}",
+		"instance_num": strconv.Itoa(instanceNumber),
+	})
+	{{end -}}
+	op := func(input {{.InType}}) (output {{.OutType}}, err error) {
+		{{.Body}}
+	}
+	for in := range input {
+		ticket, ok := breaker.Allow()
+		if !ok {
+			{{if .Prometheus}}rejections.Inc(){{end}}
+			if rejected != nil {
+				rejected <- in
+			}
+			continue
+		}
+		out, err := op(in)
+		breaker.Record(ticket, err)
+		if err != nil {
+			if failed != nil {
+				failed <- {{.FailedType}}{Input: in, Err: err}
+			}
+			continue
+		}
+		output <- out
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("CircuitBreaker", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &CircuitBreaker{
+				InputType:    "$In",
+				OutputType:   "$Out",
+				Body:         []string{"return output, nil"},
+				Window:       100,
+				MinRequests:  10,
+				Threshold:    0.5,
+				OpenDuration: 10 * time.Second,
+				Probes:       1,
+			}
+		},
+		Init: `
+		var (
+			circuitBreakerState = prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace: "shenzhen_go",
+					Subsystem: "circuit_breaker",
+					Name:      "state",
+					Help:      "State of CircuitBreaker nodes (0 = closed, 1 = half-open, 2 = open)",
+				},
+				[]string{"node_name"},
+			)
+			circuitBreakerTransitions = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Namespace: "shenzhen_go",
+					Subsystem: "circuit_breaker",
+					Name:      "transitions",
+					Help:      "State transitions of CircuitBreaker nodes",
+				},
+				[]string{"node_name", "from", "to"},
+			)
+			circuitBreakerRejections = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Namespace: "shenzhen_go",
+					Subsystem: "circuit_breaker",
+					Name:      "rejections",
+					Help:      "Inputs rejected by CircuitBreaker nodes",
+				},
+				[]string{"node_name", "instance_num"},
+			)
+		)
+
+		func init() {
+			prometheus.MustRegister(
+				circuitBreakerState,
+				circuitBreakerTransitions,
+				circuitBreakerRejections,
+			)
+		}
+		`,
+		Panels: []model.PartPanel{
+			{
+				Name: "Breaker",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<input id="circuitbreaker-enableprometheus" name="circuitbreaker-enableprometheus" type="checkbox"></input>
+					<label for="circuitbreaker-enableprometheus">Enable Prometheus metrics</label>
+				</div>
+				<div class="formfield">
+					<label for="circuitbreaker-window">Window (operations)</label>
+					<input id="circuitbreaker-window" name="circuitbreaker-window" type="number" required title="Must be a whole number, at least 1." value="100"></input>
+				</div>
+				<div class="formfield">
+					<label for="circuitbreaker-minrequests">Minimum operations</label>
+					<input id="circuitbreaker-minrequests" name="circuitbreaker-minrequests" type="number" required title="Must be a whole number." value="10"></input>
+				</div>
+				<div class="formfield">
+					<label for="circuitbreaker-threshold">Error rate threshold</label>
+					<input id="circuitbreaker-threshold" name="circuitbreaker-threshold" type="number" step="any" min="0" max="1" required title="Must be a number between 0 and 1." value="0.5"></input>
+				</div>
+				<div class="formfield">
+					<label for="circuitbreaker-openduration">Open duration</label>
+					<input id="circuitbreaker-openduration" name="circuitbreaker-openduration" type="text" required title="Must be a parseable time.Duration" value="10s"></input>
+				</div>
+				<div class="formfield">
+					<label for="circuitbreaker-probes">Half-open probes</label>
+					<input id="circuitbreaker-probes" name="circuitbreaker-probes" type="number" required title="Must be a whole number, at least 1." value="1"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Types",
+				Editor: `<div class="form">
+					<div class="formfield">
+						<label for="circuitbreaker-inputtype">Input type</label>
+						<input id="circuitbreaker-inputtype" name="circuitbreaker-inputtype" type="text"></input>
+					</div>
+					<div class="formfield">
+						<label for="circuitbreaker-outputtype">Output type</label>
+						<input id="circuitbreaker-outputtype" name="circuitbreaker-outputtype" type="text"></input>
+					</div>
+				</div>`,
+			},
+			{
+				Name:   "Imports",
+				Editor: `<div class="codeedit" id="circuitbreaker-imports"></div>`,
+			},
+			{
+				Name: "Operation",
+				Editor: `<div class="formfield">
+					<span class="link" id="circuitbreaker-format-link">Format</span>
+				</div>
+				<div class="codeedit formfield" id="circuitbreaker-body"></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A CircuitBreaker part performs an operation on each input, but stops
+				attempting the operation while it is failing too often.
+				The operation is BYO code, as in a Retry part: the body of a function
+				with the signature <code>func(input $In) (output $Out, err error)</code>.
+				Successful outputs are sent on the output pin, and failures are sent
+				with the input on the failed pin.
+			</p><p>
+				The breaker starts closed, and tracks the results of the most recent
+				operations (up to the window size). Once at least the minimum number
+				of operations has been tracked, and the fraction that failed reaches
+				the threshold, the breaker opens.
+			</p><p>
+				While open, inputs are sent to the rejected pin without attempting
+				the operation. After the open duration, the breaker becomes half-open
+				and attempts up to the number of probe operations. If they all succeed,
+				the breaker closes; if any fail, it opens again. Inputs that arrive while
+				the probes are in progress are rejected.
+			</p><p>
+				The breaker is shared by all instances. With Prometheus metrics enabled,
+				the state (0 = closed, 1 = half-open, 2 = open), state transitions, and
+				rejections are exported.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// CircuitBreaker is a part which performs an operation on each input,
+// gated by a circuit breaker.
+type CircuitBreaker struct {
+	Imports          []string      `json:"imports"`
+	Body             []string      `json:"body"`
+	InputType        string        `json:"input_type"`
+	OutputType       string        `json:"output_type"`
+	EnablePrometheus bool          `json:"enable_prometheus"`
+	Window           uint          `json:"window"`
+	MinRequests      uint          `json:"min_requests"`
+	Threshold        float64       `json:"threshold"`
+	OpenDuration     time.Duration `json:"open_duration"`
+	Probes           uint          `json:"probes"`
+}
+
+// Clone returns a clone of this CircuitBreaker.
+func (c *CircuitBreaker) Clone() model.Part {
+	c0 := *c
+	c0.Imports = append([]string(nil), c.Imports...)
+	c0.Body = append([]string(nil), c.Body...)
+	return &c0
+}
+
+// Impl returns the CircuitBreaker implementation.
+func (c *CircuitBreaker) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		InType, OutType, FailedType, Body string
+		Window, MinRequests, Probes       uint
+		Threshold                         float64
+		OpenDuration                      time.Duration
+		OpenDurationNanos                 int64
+		Prometheus                        bool
+		NodeName                          string
+	}{
+		InType:            n.PinTypes["input"].String(),
+		OutType:           n.PinTypes["output"].String(),
+		Body:              strings.Join(c.Body, "\n"),
+		Window:            c.Window,
+		MinRequests:       c.MinRequests,
+		Probes:            c.Probes,
+		Threshold:         c.Threshold,
+		OpenDuration:      c.OpenDuration,
+		OpenDurationNanos: int64(c.OpenDuration),
+		Prometheus:        c.EnablePrometheus,
+		NodeName:          n.Name,
+	}
+	params.FailedType = failedType(params.InType)
+	if strings.TrimSpace(params.Body) == "" {
+		// op has results, so it needs at least a return.
+		params.Body = "return output, nil"
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := circuitBreakerHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute circuitbreaker-head template: " + err.Error())
+	}
+	if err := circuitBreakerBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute circuitbreaker-body template: " + err.Error())
+	}
+	imps := append([]string{`"github.com/google/shenzhen-go/dev/parts"`}, c.Imports...)
+	if c.EnablePrometheus {
+		imps = append(imps,
+			`"strconv"`,
+			`"github.com/prometheus/client_golang/prometheus"`,
+		)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `close(output)
+		if failed != nil {
+			close(failed)
+		}
+		if rejected != nil {
+			close(rejected)
+		}`,
+		NeedsInit: c.EnablePrometheus,
+	}
+}
+
+// Pins returns a map declaring an input, an output, and outputs for
+// failed and rejected inputs.
+func (c *CircuitBreaker) Pins() pin.Map {
+	return pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      c.InputType,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      c.OutputType,
+		},
+		&pin.Definition{
+			Name:      "failed",
+			Direction: pin.Output,
+			Type:      failedType(c.InputType),
+		},
+		&pin.Definition{
+			Name:      "rejected",
+			Direction: pin.Output,
+			Type:      c.InputType,
+		},
+	)
+}
+
+// TypeKey returns "CircuitBreaker".
+func (c *CircuitBreaker) TypeKey() string { return "CircuitBreaker" }
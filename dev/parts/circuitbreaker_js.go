@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	circuitBreakerImportsSession, circuitBreakerBodySession *dom.AceSession
+
+	circuitBreakerOutlets = struct {
+		inputEnablePrometheus dom.Element
+		inputWindow           dom.Element
+		inputMinRequests      dom.Element
+		inputThreshold        dom.Element
+		inputOpenDuration     dom.Element
+		inputProbes           dom.Element
+		inputInputType        dom.Element
+		inputOutputType       dom.Element
+		linkFormat            dom.Element
+	}{
+		inputEnablePrometheus: doc.ElementByID("circuitbreaker-enableprometheus"),
+		inputWindow:           doc.ElementByID("circuitbreaker-window"),
+		inputMinRequests:      doc.ElementByID("circuitbreaker-minrequests"),
+		inputThreshold:        doc.ElementByID("circuitbreaker-threshold"),
+		inputOpenDuration:     doc.ElementByID("circuitbreaker-openduration"),
+		inputProbes:           doc.ElementByID("circuitbreaker-probes"),
+		inputInputType:        doc.ElementByID("circuitbreaker-inputtype"),
+		inputOutputType:       doc.ElementByID("circuitbreaker-outputtype"),
+		linkFormat:            doc.ElementByID("circuitbreaker-format-link"),
+	}
+
+	focusedCircuitBreaker *CircuitBreaker
+)
+
+func init() {
+	circuitBreakerImportsSession = setupAce("circuitbreaker-imports", dom.AceGoMode, circuitBreakerImportsChange)
+	circuitBreakerBodySession = setupAce("circuitbreaker-body", dom.AceGoMode, circuitBreakerBodyChange)
+
+	o := &circuitBreakerOutlets
+	o.inputEnablePrometheus.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.EnablePrometheus = o.inputEnablePrometheus.Get("checked").Bool()
+	})
+	o.inputWindow.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.Window = uint(o.inputWindow.Get("value").Int())
+	})
+	o.inputMinRequests.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.MinRequests = uint(o.inputMinRequests.Get("value").Int())
+	})
+	o.inputThreshold.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.Threshold = o.inputThreshold.Get("value").Float()
+	})
+	o.inputOpenDuration.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedCircuitBreaker.OpenDuration = t
+	}))
+	o.inputProbes.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.Probes = uint(o.inputProbes.Get("value").Int())
+	})
+	o.inputInputType.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.InputType = o.inputInputType.Get("value").String()
+	})
+	o.inputOutputType.AddEventListener("change", func(dom.Object) {
+		focusedCircuitBreaker.OutputType = o.inputOutputType.Get("value").String()
+	})
+	o.linkFormat.AddEventListener("click", formatHandler(circuitBreakerBodySession))
+}
+
+func circuitBreakerImportsChange(dom.Object) {
+	focusedCircuitBreaker.Imports = stripCR(strings.Split(circuitBreakerImportsSession.Value(), "\n"))
+}
+
+func circuitBreakerBodyChange(dom.Object) {
+	focusedCircuitBreaker.Body = stripCR(strings.Split(circuitBreakerBodySession.Value(), "\n"))
+}
+
+func (c *CircuitBreaker) GainFocus() {
+	focusedCircuitBreaker = c
+	o := &circuitBreakerOutlets
+	o.inputEnablePrometheus.Set("checked", c.EnablePrometheus)
+	o.inputWindow.Set("value", c.Window)
+	o.inputMinRequests.Set("value", c.MinRequests)
+	o.inputThreshold.Set("value", c.Threshold)
+	o.inputOpenDuration.Set("value", c.OpenDuration.String())
+	o.inputProbes.Set("value", c.Probes)
+	o.inputInputType.Set("value", c.InputType)
+	o.inputOutputType.Set("value", c.OutputType)
+	circuitBreakerImportsSession.SetValue(strings.Join(c.Imports, "\n"))
+	circuitBreakerBodySession.SetValue(strings.Join(c.Body, "\n"))
+}
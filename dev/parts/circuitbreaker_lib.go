@@ -0,0 +1,194 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a Breaker.
+type CircuitState int
+
+// The states of a Breaker. The values are used for the state metric.
+const (
+	CircuitClosed   CircuitState = 0
+	CircuitHalfOpen CircuitState = 1
+	CircuitOpen     CircuitState = 2
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a circuit breaker, used by the CircuitBreaker part. It tracks
+// the error rate of an operation, and stops the operation from being
+// attempted while the error rate is too high. It is safe for concurrent use.
+//
+// While closed, the results of the most recent Window operations are
+// tracked. Once at least MinRequests results are tracked and the fraction
+// of errors is at least Threshold, the breaker opens. While open, no
+// operations are allowed. After OpenDuration, the breaker becomes half-open,
+// and allows up to Probes operations. If they all succeed the breaker closes,
+// but any error opens it again.
+//
+// Each result is only counted in the state its operation was allowed in.
+// For example, a slow operation allowed while closed that finishes after
+// the breaker has become half-open is not counted as a probe.
+type Breaker struct {
+	Window       int
+	MinRequests  int
+	Threshold    float64
+	OpenDuration time.Duration
+	Probes       int
+
+	// OnStateChange, if not nil, is called (while holding the lock)
+	// whenever the state changes.
+	OnStateChange func(from, to CircuitState)
+
+	mu       sync.Mutex
+	state    CircuitState
+	epoch    uint64 // incremented on every state change
+	results  []bool // ring buffer of failures while closed
+	next     int
+	failures int
+	openedAt time.Time
+	probing  int // probes allowed while half-open
+	probed   int // probes succeeded while half-open
+}
+
+// State returns the current state.
+func (b *Breaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenExpiry(time.Now())
+	return b.state
+}
+
+// BreakerTicket is returned by Allow for each operation allowed, and passed
+// to Record with the outcome of the operation.
+type BreakerTicket struct {
+	epoch uint64
+}
+
+// Allow reports whether an operation may be attempted now. Every operation
+// allowed must be followed by a call to Record with the ticket and the
+// outcome.
+func (b *Breaker) Allow() (BreakerTicket, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenExpiry(time.Now())
+	t := BreakerTicket{epoch: b.epoch}
+	switch b.state {
+	case CircuitClosed:
+		return t, true
+	case CircuitHalfOpen:
+		if b.probing >= b.probes() {
+			return t, false
+		}
+		b.probing++
+		return t, true
+	default:
+		return t, false
+	}
+}
+
+// Record records the outcome of an operation allowed by Allow.
+func (b *Breaker) Record(t BreakerTicket, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.epoch != b.epoch {
+		// The operation was allowed before the last state change, so its
+		// result says nothing about the current state.
+		return
+	}
+	switch b.state {
+	case CircuitClosed:
+		if b.results == nil {
+			w := b.Window
+			if w < 1 {
+				w = 1
+			}
+			b.results = make([]bool, 0, w)
+		}
+		failed := err != nil
+		if len(b.results) < cap(b.results) {
+			b.results = append(b.results, failed)
+		} else {
+			if b.results[b.next] {
+				b.failures--
+			}
+			b.results[b.next] = failed
+			b.next = (b.next + 1) % len(b.results)
+		}
+		if failed {
+			b.failures++
+		}
+		if len(b.results) >= b.MinRequests && float64(b.failures) >= b.Threshold*float64(len(b.results)) && b.failures > 0 {
+			b.open(time.Now())
+		}
+	case CircuitHalfOpen:
+		if err != nil {
+			b.open(time.Now())
+			return
+		}
+		b.probed++
+		if b.probed >= b.probes() {
+			b.setState(CircuitClosed)
+		}
+	}
+}
+
+func (b *Breaker) probes() int {
+	if b.Probes < 1 {
+		return 1
+	}
+	return b.Probes
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(CircuitOpen)
+}
+
+func (b *Breaker) checkOpenExpiry(now time.Time) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.OpenDuration {
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+// setState changes the state and resets the counters used by the new state.
+func (b *Breaker) setState(s CircuitState) {
+	if s == b.state {
+		return
+	}
+	from := b.state
+	b.state = s
+	b.epoch++
+	b.results, b.next, b.failures = nil, 0, 0
+	b.probing, b.probed = 0, 0
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, s)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+	b := &Breaker{
+		Window:       4,
+		MinRequests:  4,
+		Threshold:    0.5,
+		OpenDuration: 10 * time.Millisecond,
+		Probes:       2,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	}
+	errFail := errors.New("fail")
+
+	// Not enough requests to open yet.
+	for _, err := range []error{errFail, errFail, nil} {
+		tk, ok := b.Allow()
+		if !ok {
+			t.Fatalf("Allow() = false while closed, want true")
+		}
+		b.Record(tk, err)
+	}
+	if got, want := b.State(), CircuitClosed; got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+
+	// 4th result: 2 of 4 failed, which reaches the threshold.
+	tk, _ := b.Allow()
+	b.Record(tk, nil)
+	if got, want := b.State(), CircuitOpen; got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+	if _, ok := b.Allow(); ok {
+		t.Errorf("Allow() = true while open, want false")
+	}
+
+	// After the open duration, allow only the probes.
+	time.Sleep(20 * time.Millisecond)
+	tk1, ok1 := b.Allow()
+	tk2, ok2 := b.Allow()
+	if !ok1 || !ok2 {
+		t.Fatalf("Allow() = false for probes while half-open, want true")
+	}
+	if _, ok := b.Allow(); ok {
+		t.Errorf("Allow() = true for too many probes while half-open, want false")
+	}
+	b.Record(tk1, nil)
+	b.Record(tk2, errFail)
+	if got, want := b.State(), CircuitOpen; got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+
+	// Successful probes close the breaker.
+	time.Sleep(20 * time.Millisecond)
+	tk1, _ = b.Allow()
+	tk2, _ = b.Allow()
+	b.Record(tk1, nil)
+	b.Record(tk2, nil)
+	if got, want := b.State(), CircuitClosed; got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+
+	want := []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions[%d] = %q, want %q", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	b := &Breaker{
+		Window:       2,
+		MinRequests:  2,
+		Threshold:    0.5,
+		OpenDuration: 10 * time.Millisecond,
+		Probes:       1,
+	}
+	errFail := errors.New("fail")
+
+	// A slow operation is allowed while closed, then others open the breaker.
+	slow, _ := b.Allow()
+	for i := 0; i < 2; i++ {
+		tk, _ := b.Allow()
+		b.Record(tk, errFail)
+	}
+	if got, want := b.State(), CircuitOpen; got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+
+	// Once half-open, the slow operation succeeds. It wasn't a probe, so
+	// the breaker should stay half-open, and the probe should be allowed.
+	time.Sleep(20 * time.Millisecond)
+	b.Record(slow, nil)
+	if got, want := b.State(), CircuitHalfOpen; got != want {
+		t.Fatalf("State() after stale success = %v, want %v", got, want)
+	}
+	probe, ok := b.Allow()
+	if !ok {
+		t.Fatalf("Allow() = false for probe while half-open, want true")
+	}
+
+	// A stale failure doesn't reopen the breaker either.
+	b.Record(slow, errFail)
+	if got, want := b.State(), CircuitHalfOpen; got != want {
+		t.Fatalf("State() after stale failure = %v, want %v", got, want)
+	}
+	b.Record(probe, nil)
+	if got, want := b.State(), CircuitClosed; got != want {
+		t.Fatalf("State() after probe = %v, want %v", got, want)
+	}
+}
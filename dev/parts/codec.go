@@ -0,0 +1,343 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+// CodecFormat is a data format for Decode and Encode parts.
+type CodecFormat string
+
+// Values for CodecFormat.
+const (
+	CodecJSON   CodecFormat = "json"
+	CodecNDJSON CodecFormat = "ndjson"
+	CodecCSV    CodecFormat = "csv"
+	CodecGob    CodecFormat = "gob"
+)
+
+const codecFormatSelect = `
+	<option value="json" selected>JSON (one value per message)</option>
+	<option value="ndjson">Newline-delimited JSON stream</option>
+	<option value="csv">CSV stream</option>
+	<option value="gob">gob stream</option>`
+
+var (
+	decodePins = pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      "[]byte",
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      "$T",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+
+	encodePins = pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      "$T",
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      "[]byte",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+
+	// Stream formats are decoded from a pipe, written to by a goroutine
+	// copying the inputs. If the decoder stops early, the rest of the
+	// stream is discarded.
+	decodeBodyTmpl = template.Must(template.New("decode-body").Parse(`
+	{{if eq .Format "json" -}}
+	for in := range input {
+		var v {{.Type}}
+		if err := json.Unmarshal(in, &v); err != nil {
+			if errors != nil {
+				errors <- err
+			}
+			continue
+		}
+		output <- v
+	}
+	{{- else -}}
+	pr, pw := io.Pipe()
+	go func() {
+		for in := range input {
+			pw.Write(in)
+		}
+		pw.Close()
+	}()
+	{{if eq .Format "ndjson" -}}
+	br := bufio.NewReader(pr)
+	for {
+		line, rerr := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var v {{.Type}}
+			if err := json.Unmarshal(line, &v); err != nil {
+				if errors != nil {
+					errors <- err
+				}
+			} else {
+				output <- v
+			}
+		}
+		if rerr != nil {
+			break
+		}
+	}
+	{{- else -}}
+	dec := {{if eq .Format "csv"}}parts.NewCSVDecoder(pr){{else}}gob.NewDecoder(pr){{end}}
+	for {
+		var v {{.Type}}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors != nil {
+				errors <- err
+			}
+			{{if eq .Format "gob" -}}
+			// The gob stream can't be resynchronised after an error.
+			io.Copy(ioutil.Discard, pr)
+			break
+			{{- else -}}
+			continue
+			{{- end}}
+		}
+		output <- v
+	}
+	{{- end}}
+	{{- end}}`))
+
+	encodeBodyTmpl = template.Must(template.New("encode-body").Parse(`
+	{{if eq .Format "json" "ndjson" -}}
+	for in := range input {
+		b, err := json.Marshal(in)
+		if err != nil {
+			if errors != nil {
+				errors <- err
+			}
+			continue
+		}
+		{{if eq .Format "ndjson"}}b = append(b, '\n'){{end}}
+		output <- b
+	}
+	{{- else -}}
+	var buf bytes.Buffer
+	enc := {{if eq .Format "csv"}}parts.NewCSVEncoder(&buf){{else}}gob.NewEncoder(&buf){{end}}
+	for in := range input {
+		if err := enc.Encode(in); err != nil {
+			if errors != nil {
+				errors <- err
+			}
+			buf.Reset()
+			continue
+		}
+		output <- append([]byte(nil), buf.Bytes()...)
+		buf.Reset()
+	}
+	{{- end}}`))
+)
+
+func init() {
+	model.RegisterPartType("Decode", "Encoding", &model.PartType{
+		New: func() model.Part { return &Decode{Format: CodecJSON} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Decode",
+				Editor: `<div class="form"><div class="formfield">
+					<label for="decode-format">Format</label>
+					<select id="decode-format" name="decode-format">` + codecFormatSelect + `
+					</select>
+				</div></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Decode part decodes bytes into values of any type, which is
+				inferred from the channel connected to the output. Errors are sent
+				to the errors output if it is connected, and otherwise ignored.
+			</p><p>
+				In JSON format, each input is a single JSON value, decoded with
+				<code>encoding/json</code>.
+			</p><p>
+				The other formats treat the inputs as consecutive pieces of one
+				stream, which need not be split at value boundaries. Newline-delimited
+				JSON has one JSON value on each line. CSV has a header row, and then
+				one row for each value, which must be a struct (or <code>[]string</code>);
+				columns are mapped to fields by name, or by a <code>csv:"name"</code>
+				field tag. Gob streams are decoded with <code>encoding/gob</code>;
+				because a gob stream can't continue after an error, the rest of the
+				stream is discarded.
+			</p><p>
+				In stream formats, multiplicity must be 1.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("Encode", "Encoding", &model.PartType{
+		New: func() model.Part { return &Encode{Format: CodecJSON} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Encode",
+				Editor: `<div class="form"><div class="formfield">
+					<label for="encode-format">Format</label>
+					<select id="encode-format" name="encode-format">` + codecFormatSelect + `
+					</select>
+				</div></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				An Encode part encodes values of any type into bytes, in the same
+				formats as the Decode part. Errors are sent to the errors output if
+				it is connected, and otherwise ignored.
+			</p><p>
+				In JSON format, each value is encoded separately. In the other formats,
+				the outputs are consecutive pieces of one stream: newline-delimited
+				JSON ends each value with a newline, CSV starts with a header row, and
+				gob sends type information before the first value of each type.
+			</p><p>
+				In stream formats, multiplicity must be 1.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// Decode is a part which decodes bytes into values.
+type Decode struct {
+	Format CodecFormat `json:"format"`
+}
+
+// Clone returns a clone of this Decode.
+func (d *Decode) Clone() model.Part {
+	d0 := *d
+	return &d0
+}
+
+// Impl returns the Decode implementation.
+func (d *Decode) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Format CodecFormat
+		Type   string
+	}{
+		Format: d.Format,
+		Type:   n.TypeParams["$T"].String(),
+	}
+	var imps []string
+	switch d.Format {
+	case CodecJSON:
+		imps = []string{`"encoding/json"`}
+	case CodecNDJSON:
+		imps = []string{`"bufio"`, `"bytes"`, `"encoding/json"`, `"io"`}
+	case CodecCSV:
+		imps = []string{`"io"`, `"github.com/google/shenzhen-go/dev/parts"`}
+	case CodecGob:
+		imps = []string{`"encoding/gob"`, `"io"`, `"io/ioutil"`}
+	default:
+		panic("unknown format " + d.Format)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := decodeBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute decode-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    b.String(),
+		Tail: `close(output)
+		if errors != nil {
+			close(errors)
+		}`,
+	}
+}
+
+// Pins returns a map declaring a []byte input, an output, and an
+// errors output.
+func (d *Decode) Pins() pin.Map { return decodePins }
+
+// TypeKey returns "Decode".
+func (d *Decode) TypeKey() string { return "Decode" }
+
+// Encode is a part which encodes values into bytes.
+type Encode struct {
+	Format CodecFormat `json:"format"`
+}
+
+// Clone returns a clone of this Encode.
+func (e *Encode) Clone() model.Part {
+	e0 := *e
+	return &e0
+}
+
+// Impl returns the Encode implementation.
+func (e *Encode) Impl(n *model.Node) model.PartImpl {
+	var imps []string
+	switch e.Format {
+	case CodecJSON, CodecNDJSON:
+		imps = []string{`"encoding/json"`}
+	case CodecCSV:
+		imps = []string{`"bytes"`, `"github.com/google/shenzhen-go/dev/parts"`}
+	case CodecGob:
+		imps = []string{`"bytes"`, `"encoding/gob"`}
+	default:
+		panic("unknown format " + e.Format)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := encodeBodyTmpl.Execute(b, struct{ Format CodecFormat }{e.Format}); err != nil {
+		panic("couldn't execute encode-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    b.String(),
+		Tail: `close(output)
+		if errors != nil {
+			close(errors)
+		}`,
+	}
+}
+
+// Pins returns a map declaring an input, a []byte output, and an
+// errors output.
+func (e *Encode) Pins() pin.Map { return encodePins }
+
+// TypeKey returns "Encode".
+func (e *Encode) TypeKey() string { return "Encode" }
@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import "github.com/google/shenzhen-go/dev/dom"
+
+var (
+	selectDecodeFormat = doc.ElementByID("decode-format")
+	selectEncodeFormat = doc.ElementByID("encode-format")
+
+	focusedDecode *Decode
+	focusedEncode *Encode
+)
+
+func init() {
+	selectDecodeFormat.AddEventListener("change", func(dom.Object) {
+		focusedDecode.Format = CodecFormat(selectDecodeFormat.Get("value").String())
+	})
+	selectEncodeFormat.AddEventListener("change", func(dom.Object) {
+		focusedEncode.Format = CodecFormat(selectEncodeFormat.Get("value").String())
+	})
+}
+
+func (d *Decode) GainFocus() {
+	focusedDecode = d
+	selectDecodeFormat.Set("value", d.Format)
+}
+
+func (e *Encode) GainFocus() {
+	focusedEncode = e
+	selectEncodeFormat.Set("value", e.Format)
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// csvFields returns the indexes and column names of the fields of
+// struct type t that can be read and written as CSV. The column name is
+// the field name, unless overridden with a `csv:"name"` tag. Fields
+// tagged `csv:"-"` and unexported fields are skipped.
+func csvFields(t reflect.Type) (idx []int, names []string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		idx = append(idx, i)
+		names = append(names, name)
+	}
+	return idx, names
+}
+
+// CSVDecoder reads CSV records into structs. The first record is a header,
+// which maps columns to struct fields by name (case-insensitively). Columns
+// without a matching field are ignored.
+type CSVDecoder struct {
+	r    *csv.Reader
+	typ  reflect.Type
+	cols []int // field index for each column, or -1
+}
+
+// NewCSVDecoder returns a CSVDecoder reading from r.
+func NewCSVDecoder(r io.Reader) *CSVDecoder {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return &CSVDecoder{r: cr}
+}
+
+// Decode reads the next record into v, which must be a pointer to a
+// struct, or a pointer to a []string (which receives the whole record).
+// It returns io.EOF when there are no more records. After an error
+// decoding a record, Decode can be called again to read the next one.
+func (d *CSVDecoder) Decode(v interface{}) error {
+	if sp, ok := v.(*[]string); ok {
+		rec, err := d.r.Read()
+		if err != nil {
+			return err
+		}
+		*sp = append((*sp)[:0], rec...)
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("CSV can only be decoded into a pointer to a struct, not %T", v)
+	}
+	rv = rv.Elem()
+	if d.cols == nil || d.typ != rv.Type() {
+		if err := d.readHeader(rv.Type()); err != nil {
+			return err
+		}
+	}
+	rec, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	for i, s := range rec {
+		if i >= len(d.cols) || d.cols[i] < 0 {
+			continue
+		}
+		fi := d.cols[i]
+		if err := setCSVField(rv.Field(fi), s); err != nil {
+			return fmt.Errorf("column %d (field %s): %v", i+1, rv.Type().Field(fi).Name, err)
+		}
+	}
+	return nil
+}
+
+func (d *CSVDecoder) readHeader(t reflect.Type) error {
+	hdr, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	idx, names := csvFields(t)
+	d.typ, d.cols = t, make([]int, len(hdr))
+	for i, h := range hdr {
+		d.cols[i] = -1
+		for j, n := range names {
+			if strings.EqualFold(strings.TrimSpace(h), n) {
+				d.cols[i] = idx[j]
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func setCSVField(f reflect.Value, s string) error {
+	if tu, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	default:
+		return fmt.Errorf("unsupported field type %v", f.Type())
+	}
+	return nil
+}
+
+// CSVEncoder writes structs as CSV records. Before the first record, it
+// writes a header with the column name of each field.
+type CSVEncoder struct {
+	w   *csv.Writer
+	typ reflect.Type
+	idx []int
+	rec []string
+}
+
+// NewCSVEncoder returns a CSVEncoder writing to w.
+func NewCSVEncoder(w io.Writer) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w)}
+}
+
+// Encode writes v, which must be a struct or a []string, as a record,
+// preceded by the header if it is the first struct.
+func (e *CSVEncoder) Encode(v interface{}) error {
+	if ss, ok := v.([]string); ok {
+		return e.write(ss)
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("CSV can only be encoded from a struct, not %T", v)
+	}
+	if e.typ != rv.Type() {
+		idx, names := csvFields(rv.Type())
+		if err := e.write(names); err != nil {
+			return err
+		}
+		e.typ, e.idx = rv.Type(), idx
+	}
+	e.rec = e.rec[:0]
+	for _, i := range e.idx {
+		e.rec = append(e.rec, formatCSVField(rv.Field(i)))
+	}
+	return e.write(e.rec)
+}
+
+func (e *CSVEncoder) write(rec []string) error {
+	if err := e.w.Write(rec); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func formatCSVField(f reflect.Value) string {
+	if tm, ok := f.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String()
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, f.Type().Bits())
+	default:
+		return fmt.Sprint(f.Interface())
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvTestRow struct {
+	Name    string
+	Count   int `csv:"count"`
+	Ratio   float64
+	OK      bool
+	When    time.Time
+	Ignored string `csv:"-"`
+	private int
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	when := time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC)
+	rows := []csvTestRow{
+		{Name: "a, b", Count: 1, Ratio: 0.5, OK: true, When: when},
+		{Name: "c", Count: -2, Ratio: 1e10, When: when.Add(time.Hour)},
+	}
+	var buf bytes.Buffer
+	enc := NewCSVEncoder(&buf)
+	for _, r := range rows {
+		r.Ignored = "x"
+		if err := enc.Encode(r); err != nil {
+			t.Fatalf("Encode(%v) = error %v", r, err)
+		}
+	}
+	wantHeader := "Name,count,Ratio,OK,When\n"
+	if got := buf.String(); !strings.HasPrefix(got, wantHeader) {
+		t.Errorf("encoded CSV = %q, want prefix %q", got, wantHeader)
+	}
+
+	dec := NewCSVDecoder(&buf)
+	for i, want := range rows {
+		var got csvTestRow
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode() #%d = error %v", i, err)
+		}
+		if got != want {
+			t.Errorf("Decode() #%d = %+v, want %+v", i, got, want)
+		}
+	}
+	var extra csvTestRow
+	if err := dec.Decode(&extra); err != io.EOF {
+		t.Errorf("Decode() at end = error %v, want io.EOF", err)
+	}
+}
+
+func TestCSVDecoderHeaderMapping(t *testing.T) {
+	in := "extra,COUNT,name\nq,3,x\nr,notanumber,y\ns,4,z\n"
+	dec := NewCSVDecoder(strings.NewReader(in))
+	want := []struct {
+		row csvTestRow
+		err bool
+	}{
+		{row: csvTestRow{Name: "x", Count: 3}},
+		{err: true},
+		{row: csvTestRow{Name: "z", Count: 4}},
+	}
+	for i, w := range want {
+		var got csvTestRow
+		err := dec.Decode(&got)
+		if w.err {
+			if err == nil {
+				t.Errorf("Decode() #%d = %+v, want error", i, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Decode() #%d = error %v", i, err)
+		}
+		if got != w.row {
+			t.Errorf("Decode() #%d = %+v, want %+v", i, got, w.row)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bufio"
+	"bytes"
+	"text/template"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	connLinesPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "conns",
+			Direction: pin.Input,
+			Type:      "*parts.Conn",
+		},
+		&pin.Definition{
+			Name:      "sessions",
+			Direction: pin.Output,
+			Type:      "parts.ConnMessages",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+
+	// Each connection has a goroutine reading messages and another writing
+	// them. Errors from reading after the connection is closed (by the
+	// writer) are expected, so aren't reported.
+	connLinesBodyTmpl = template.Must(template.New("connlines-body").Parse(`
+	var rw sync.WaitGroup
+	for c := range conns {
+		in := make(chan string, {{.BufferSize}})
+		out := make(chan string, {{.BufferSize}})
+		rw.Add(2)
+		go func(c *parts.Conn) {
+			defer rw.Done()
+			defer close(in)
+			sc := bufio.NewScanner(c)
+			sc.Buffer(make([]byte, 0, 4096), {{.MaxSize}})
+			sc.Split({{.Split}})
+			for sc.Scan() {
+				select {
+				case in <- sc.Text():
+				case <-c.Done():
+					return
+				}
+			}
+			if err := sc.Err(); err != nil && errors != nil {
+				select {
+				case <-c.Done():
+				default:
+					errors <- fmt.Errorf("reading from %v: %v", c.RemoteAddr(), err)
+				}
+			}
+		}(c)
+		go func(c *parts.Conn) {
+			defer rw.Done()
+			defer c.Close()
+			w := bufio.NewWriter(c)
+			var err error
+			for msg := range out {
+				if err != nil {
+					// Discard the rest.
+					continue
+				}
+				{{if .Lines -}}
+				_, err = w.WriteString(msg + "\n")
+				{{- else -}}
+				err = parts.WriteFrame(w, msg)
+				{{- end}}
+				if err == nil && len(out) == 0 {
+					err = w.Flush()
+				}
+				if err != nil {
+					c.Close()
+					if errors != nil {
+						errors <- fmt.Errorf("writing to %v: %v", c.RemoteAddr(), err)
+					}
+				}
+			}
+			if err == nil {
+				if err := w.Flush(); err != nil && errors != nil {
+					errors <- fmt.Errorf("writing to %v: %v", c.RemoteAddr(), err)
+				}
+			}
+		}(c)
+		sessions <- parts.ConnMessages{
+			Conn: c,
+			In:   in,
+			Out:  out,
+		}
+	}
+	rw.Wait()`))
+)
+
+func init() {
+	model.RegisterPartType("ConnLines", "Network", &model.PartType{
+		New: func() model.Part {
+			return &ConnLines{
+				Framing: ConnFramingLines,
+				MaxSize: 64 * 1024,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Messages",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="connlines-framing">Framing</label>
+					<select id="connlines-framing" name="connlines-framing">
+						<option value="lines" selected>Lines</option>
+						<option value="frames">Length-prefixed frames</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="connlines-maxsize">Maximum message size</label>
+					<input id="connlines-maxsize" name="connlines-maxsize" type="number" required min="1" value="65536"></input>
+				</div>
+				<div class="formfield">
+					<label for="connlines-buffersize">Buffer size</label>
+					<input id="connlines-buffersize" name="connlines-buffersize" type="number" min="0" value="0"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A ConnLines part turns each connection received (from a TCPListener
+				or TCPDialer part) into channels of messages, and sends them to
+				sessions as a <code>parts.ConnMessages</code>. Messages read from
+				the connection arrive on <code>In</code>, and messages sent to
+				<code>Out</code> are written to the connection.
+			</p><p>
+				With lines framing, each message is a line of text, without the
+				line ending. A <code>"\n"</code> is written after each message sent.
+				With length-prefixed framing, each message can contain any bytes,
+				and is preceded by its length as a 4-byte, big-endian, unsigned
+				integer.
+			</p><p>
+				<code>In</code> is closed at the end of the input, or if there's an
+				error reading. Closing <code>Out</code> closes the connection, after
+				writing the messages already sent to it. So to finish with a
+				connection, close <code>Out</code>; this is also needed when the
+				other end has closed it. Messages longer than the maximum size
+				are an error. Errors are sent to errors, if it is connected.
+			</p><p>
+				The buffer size is the capacity of the <code>In</code> and
+				<code>Out</code> channels.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// ConnLines is a part which splits connections into messages.
+type ConnLines struct {
+	Framing    ConnFraming `json:"framing,omitempty"`
+	MaxSize    int         `json:"max_size,omitempty"`
+	BufferSize uint        `json:"buffer_size,omitempty"`
+}
+
+// Clone returns a clone of this ConnLines.
+func (l *ConnLines) Clone() model.Part {
+	l0 := *l
+	return &l0
+}
+
+// Impl returns the ConnLines implementation.
+func (l *ConnLines) Impl(*model.Node) model.PartImpl {
+	params := struct {
+		Lines      bool
+		Split      string
+		MaxSize    int
+		BufferSize uint
+	}{
+		MaxSize:    l.MaxSize,
+		BufferSize: l.BufferSize,
+	}
+	if params.MaxSize <= 0 {
+		params.MaxSize = bufio.MaxScanTokenSize
+	}
+	switch l.Framing {
+	case "", ConnFramingLines:
+		params.Lines = true
+		params.Split = "bufio.ScanLines"
+	case ConnFramingFrames:
+		params.Split = "parts.ScanFrames"
+	default:
+		panic("unknown framing " + l.Framing)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := connLinesBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute connlines-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"bufio"`,
+			`"fmt"`,
+			`"sync"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: b.String(),
+		Tail: `close(sessions)
+		if errors != nil {
+			close(errors)
+		}`,
+	}
+}
+
+// Pins returns a map declaring a conns input and sessions and errors outputs.
+func (l *ConnLines) Pins() pin.Map { return connLinesPins }
+
+// TypeKey returns "ConnLines".
+func (l *ConnLines) TypeKey() string { return "ConnLines" }
@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import "github.com/google/shenzhen-go/dev/dom"
+
+var (
+	selectConnLinesFraming   = doc.ElementByID("connlines-framing")
+	inputConnLinesMaxSize    = doc.ElementByID("connlines-maxsize")
+	inputConnLinesBufferSize = doc.ElementByID("connlines-buffersize")
+
+	focusedConnLines *ConnLines
+)
+
+func init() {
+	selectConnLinesFraming.AddEventListener("change", func(dom.Object) {
+		focusedConnLines.Framing = ConnFraming(selectConnLinesFraming.Get("value").String())
+	})
+	inputConnLinesMaxSize.AddEventListener("change", func(dom.Object) {
+		focusedConnLines.MaxSize = inputConnLinesMaxSize.Get("value").Int()
+	})
+	inputConnLinesBufferSize.AddEventListener("change", func(dom.Object) {
+		focusedConnLines.BufferSize = uint(inputConnLinesBufferSize.Get("value").Int())
+	})
+}
+
+func (l *ConnLines) GainFocus() {
+	focusedConnLines = l
+	selectConnLinesFraming.Set("value", l.Framing)
+	inputConnLinesMaxSize.Set("value", l.MaxSize)
+	inputConnLinesBufferSize.Set("value", l.BufferSize)
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	dedupPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      "$T",
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      "$T",
+		},
+		&pin.Definition{
+			Name:      "dup",
+			Direction: pin.Output,
+			Type:      "$T",
+		},
+	)
+
+	// The seen set is a map from key to list element. In LRU mode the list
+	// is ordered from most to least recently seen; in TTL mode it is ordered
+	// by expiry, oldest first.
+	dedupHeadTmpl = template.Must(template.New("dedup-head").Parse(`
+	{{if .TTL -}}
+	const ttl = {{.TTLNanos}} // {{.TTLString}}
+	type dedupEntry struct {
+		key     interface{}
+		expires time.Time
+	}
+	{{end -}}
+	{{if .Size}}const size = {{.Size}}{{end}}
+	{{if .Mult}}var mu sync.RWMutex{{end}}
+	seen := make(map[interface{}]*list.Element)
+	order := list.New()`))
+
+	dedupBodyTmpl = template.Must(template.New("dedup-body").Parse(`
+	for in := range input {
+		key := {{.Key}}
+		{{if .TTL -}}
+		{{if .Mult -}}
+		// Most duplicates can be found with only the read lock.
+		mu.RLock()
+		e, ok := seen[key]
+		isDup := ok && time.Now().Before(e.Value.(dedupEntry).expires)
+		mu.RUnlock()
+		if isDup {
+			if dup != nil {
+				dup <- in
+			}
+			continue
+		}
+		mu.Lock()
+		{{end -}}
+		now := time.Now()
+		for f := order.Front(); f != nil && !now.Before(f.Value.(dedupEntry).expires); f = order.Front() {
+			order.Remove(f)
+			delete(seen, f.Value.(dedupEntry).key)
+		}
+		if _, ok := seen[key]; ok {
+			{{if .Mult}}mu.Unlock(){{end}}
+			if dup != nil {
+				dup <- in
+			}
+			continue
+		}
+		seen[key] = order.PushBack(dedupEntry{key: key, expires: now.Add(ttl)})
+		{{if .Size -}}
+		if order.Len() > size {
+			f := order.Front()
+			order.Remove(f)
+			delete(seen, f.Value.(dedupEntry).key)
+		}
+		{{end -}}
+		{{else -}}
+		{{if .Mult}}mu.Lock(){{end}}
+		if e, ok := seen[key]; ok {
+			order.MoveToFront(e)
+			{{if .Mult}}mu.Unlock(){{end}}
+			if dup != nil {
+				dup <- in
+			}
+			continue
+		}
+		seen[key] = order.PushFront(key)
+		if order.Len() > size {
+			b := order.Back()
+			order.Remove(b)
+			delete(seen, b.Value)
+		}
+		{{end -}}
+		{{if .Mult}}mu.Unlock(){{end}}
+		output <- in
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("Dedup", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Dedup{
+				Key:  "in",
+				Mode: DedupLRU,
+				Size: 10000,
+				TTL:  time.Minute,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Dedup",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="dedup-key">Key expression</label>
+					<input id="dedup-key" name="dedup-key" type="text" required title="Must be a Go expression using in" value="in"></input>
+				</div>
+				<div class="formfield">
+					<label for="dedup-mode">Mode</label>
+					<select id="dedup-mode" name="dedup-mode">
+						<option value="lru" selected>LRU (remember most recent keys)</option>
+						<option value="ttl">TTL (remember keys for a duration)</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="dedup-size">Maximum keys</label>
+					<input id="dedup-size" name="dedup-size" type="number" required title="Must be a whole number." value="10000"></input>
+				</div>
+				<div class="formfield">
+					<label for="dedup-ttl">TTL</label>
+					<input id="dedup-ttl" name="dedup-ttl" type="text" required title="Must be a parseable time.Duration" value="1m"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Dedup part passes each input to the output, unless it is a
+				duplicate of an input seen recently. Duplicates are sent to the
+				dup output instead, or discarded if dup is not connected.
+			</p><p>
+				Inputs are compared by key. The key expression is a Go expression
+				in terms of the input value <code>in</code>, for example
+				<code>in.RequestID</code>, and must produce a comparable value.
+			</p><p>
+				The set of seen keys is bounded. In LRU mode, only the most recently
+				seen keys (up to the maximum) are remembered; seeing a duplicate
+				makes its key recent again. In TTL mode, keys are remembered for the
+				TTL after they are first seen, and the maximum (if not 0) limits how
+				many keys are remembered at once.
+			</p><p>
+				All instances share the same set of seen keys.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// DedupMode is how a Dedup part decides which keys to forget.
+type DedupMode string
+
+// Dedup modes.
+const (
+	DedupLRU DedupMode = "lru" // Least recently seen
+	DedupTTL DedupMode = "ttl" // Seen longer ago than the TTL
+)
+
+// Dedup is a part which drops duplicate inputs.
+type Dedup struct {
+	Key  string        `json:"key"`
+	Mode DedupMode     `json:"mode"`
+	Size uint          `json:"size"`
+	TTL  time.Duration `json:"ttl,omitempty"`
+}
+
+// Clone returns a clone of this Dedup.
+func (d *Dedup) Clone() model.Part {
+	d0 := *d
+	return &d0
+}
+
+// Impl returns the Dedup implementation.
+func (d *Dedup) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Key       string
+		Size      uint
+		TTL, Mult bool
+		TTLNanos  int64
+		TTLString string
+	}{
+		Key:       d.Key,
+		Size:      d.Size,
+		Mult:      n.Multiplicity != "1",
+		TTLNanos:  int64(d.TTL),
+		TTLString: d.TTL.String(),
+	}
+	if params.Key == "" {
+		params.Key = "in"
+	}
+	switch d.Mode {
+	case DedupLRU:
+		if params.Size < 1 {
+			params.Size = 1
+		}
+	case DedupTTL:
+		params.TTL = true
+	default:
+		panic("unknown mode " + d.Mode)
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := dedupHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute dedup-head template: " + err.Error())
+	}
+	if err := dedupBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute dedup-body template: " + err.Error())
+	}
+	imps := []string{`"container/list"`}
+	if params.TTL {
+		imps = append(imps, `"time"`)
+	}
+	if params.Mult {
+		imps = append(imps, `"sync"`)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `close(output)
+		if dup != nil {
+			close(dup)
+		}`,
+	}
+}
+
+// Pins returns a map declaring an input, an output, and an output
+// for duplicates.
+func (d *Dedup) Pins() pin.Map { return dedupPins }
+
+// TypeKey returns "Dedup".
+func (d *Dedup) TypeKey() string { return "Dedup" }
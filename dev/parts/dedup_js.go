@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	inputDedupKey   = doc.ElementByID("dedup-key")
+	selectDedupMode = doc.ElementByID("dedup-mode")
+	inputDedupSize  = doc.ElementByID("dedup-size")
+	inputDedupTTL   = doc.ElementByID("dedup-ttl")
+
+	focusedDedup *Dedup
+)
+
+func init() {
+	inputDedupKey.AddEventListener("change", func(dom.Object) {
+		focusedDedup.Key = inputDedupKey.Get("value").String()
+	})
+	selectDedupMode.AddEventListener("change", func(dom.Object) {
+		focusedDedup.Mode = DedupMode(selectDedupMode.Get("value").String())
+	})
+	inputDedupSize.AddEventListener("change", func(dom.Object) {
+		focusedDedup.Size = uint(inputDedupSize.Get("value").Int())
+	})
+	inputDedupTTL.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedDedup.TTL = t
+	}))
+}
+
+func (d *Dedup) GainFocus() {
+	focusedDedup = d
+	inputDedupKey.Set("value", d.Key)
+	selectDedupMode.Set("value", d.Mode)
+	inputDedupSize.Set("value", d.Size)
+	inputDedupTTL.Set("value", d.TTL.String())
+}
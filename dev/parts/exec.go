@@ -0,0 +1,251 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	execPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "stdin",
+			Direction: pin.Input,
+			Type:      "string",
+		},
+		&pin.Definition{
+			Name:      "cancel",
+			Direction: pin.Input,
+			Type:      "struct{}",
+		},
+		&pin.Definition{
+			Name:      "stdout",
+			Direction: pin.Output,
+			Type:      "string",
+		},
+		&pin.Definition{
+			Name:      "stderr",
+			Direction: pin.Output,
+			Type:      "string",
+		},
+		&pin.Definition{
+			Name:      "status",
+			Direction: pin.Output,
+			Type:      "parts.ExecStatus",
+		},
+	)
+
+	execBodyTmpl = template.Must(template.New("exec-body").Parse(`
+	run := func(in <-chan string) {
+		cmd := exec.Command({{printf "%q" .Command}}{{range .Args}}, {{printf "%q" .}}{{end}})
+		{{if .Dir -}}
+		cmd.Dir = {{printf "%q" .Dir}}
+		{{end -}}
+		st := parts.ExecCommand(cmd, parts.ExecPipes{
+			Stdin:     in,
+			Newlines:  {{.Newlines}},
+			Stdout:    stdout,
+			Stderr:    stderr,
+			Cancel:    cancel,
+			KillDelay: {{.KillDelayNanos}}, // {{.KillDelay}}
+		})
+		if status != nil {
+			status <- st
+		}
+	}
+	{{if .Once -}}
+	run(stdin)
+	if stdin != nil {
+		// Discard any input the process didn't read.
+		for range stdin {
+		}
+	}
+	{{- else -}}
+	for s := range stdin {
+		select {
+		case <-cancel:
+			// Cancelled, so discard the rest.
+			continue
+		default:
+		}
+		in := make(chan string, 1)
+		in <- s
+		close(in)
+		run(in)
+	}
+	{{- end}}`))
+)
+
+func init() {
+	model.RegisterPartType("Exec", "General", &model.PartType{
+		New: func() model.Part {
+			return &Exec{
+				Command:   "cat",
+				Mode:      ExecEachInput,
+				KillDelay: 5 * time.Second,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Command",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="exec-command">Command</label>
+					<input id="exec-command" name="exec-command" type="text" required title="Name or path of the program to run"></input>
+				</div>
+				<div class="formfield">
+					<label for="exec-args">Arguments (one per line)</label>
+					<textarea id="exec-args" name="exec-args" rows="5" cols="60"></textarea>
+				</div>
+				<div class="formfield">
+					<label for="exec-dir">Working directory</label>
+					<input id="exec-dir" name="exec-dir" type="text" title="Leave empty for the current directory"></input>
+				</div>
+				<div class="formfield">
+					<label for="exec-mode">Run</label>
+					<select id="exec-mode" name="exec-mode">
+						<option value="each" selected>Once per input</option>
+						<option value="once">Once, streaming all input</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<input id="exec-newlines" name="exec-newlines" type="checkbox"></input>
+					<label for="exec-newlines">Write a newline after each input</label>
+				</div>
+				<div class="formfield">
+					<label for="exec-killdelay">Kill delay</label>
+					<input id="exec-killdelay" name="exec-killdelay" type="text" required title="Must be a parseable time.Duration" value="5s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				An Exec part runs a command, connecting its standard input,
+				standard output, and standard error to channels. The command is
+				run directly, not by a shell, so arguments are passed exactly as
+				written.
+			</p><p>
+				When run once per input, the command is run for each value
+				received on stdin, with that value as its standard input. When run
+				once, the command is started straight away, and every value
+				received on stdin is written to its standard input, which is closed
+				when stdin is closed. Either way, a newline can be written after
+				each value.
+			</p><p>
+				Each line of standard output and standard error is sent to stdout
+				and stderr, and when the command finishes, its exit code and any
+				error are sent to status, as a <code>parts.ExecStatus</code>.
+				Outputs that aren't connected are discarded.
+			</p><p>
+				Each command runs in its own process group. Closing cancel
+				interrupts the process group of any running command (as with
+				Ctrl-C), and then kills it if it hasn't finished after the kill
+				delay, unless the delay is zero. After cancel is closed, no more
+				commands are started, and further input is discarded.
+			</p><p>
+				Multiplicity limits the number of commands run at once. When run
+				once, each instance runs its own command, sharing the input.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// ExecMode is when an Exec part runs its command.
+type ExecMode string
+
+// Values for ExecMode.
+const (
+	ExecEachInput ExecMode = "each"
+	ExecOnce      ExecMode = "once"
+)
+
+// Exec is a part which runs a command.
+type Exec struct {
+	Command   string        `json:"command"`
+	Args      []string      `json:"args,omitempty"`
+	Dir       string        `json:"dir,omitempty"`
+	Mode      ExecMode      `json:"mode,omitempty"`
+	Newlines  bool          `json:"newlines,omitempty"`
+	KillDelay time.Duration `json:"kill_delay,omitempty"`
+}
+
+// Clone returns a clone of this Exec.
+func (e *Exec) Clone() model.Part {
+	e0 := *e
+	e0.Args = append([]string(nil), e.Args...)
+	return &e0
+}
+
+// Impl returns the Exec implementation.
+func (e *Exec) Impl(*model.Node) model.PartImpl {
+	params := struct {
+		Command        string
+		Args           []string
+		Dir            string
+		Once, Newlines bool
+		KillDelay      time.Duration
+		KillDelayNanos int64
+	}{
+		Command:        e.Command,
+		Args:           e.Args,
+		Dir:            e.Dir,
+		Newlines:       e.Newlines,
+		KillDelay:      e.KillDelay,
+		KillDelayNanos: int64(e.KillDelay),
+	}
+	switch e.Mode {
+	case "", ExecEachInput:
+	case ExecOnce:
+		params.Once = true
+	default:
+		panic("unknown mode " + e.Mode)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := execBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute exec-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"os/exec"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: b.String(),
+		Tail: `if stdout != nil {
+			close(stdout)
+		}
+		if stderr != nil {
+			close(stderr)
+		}
+		if status != nil {
+			close(status)
+		}`,
+	}
+}
+
+// Pins returns a map declaring stdin and cancel inputs, and stdout, stderr
+// and status outputs.
+func (e *Exec) Pins() pin.Map { return execPins }
+
+// TypeKey returns "Exec".
+func (e *Exec) TypeKey() string { return "Exec" }
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	execOutlets = struct {
+		inputCommand   dom.Element
+		textareaArgs   dom.Element
+		inputDir       dom.Element
+		selectMode     dom.Element
+		inputNewlines  dom.Element
+		inputKillDelay dom.Element
+	}{
+		inputCommand:   doc.ElementByID("exec-command"),
+		textareaArgs:   doc.ElementByID("exec-args"),
+		inputDir:       doc.ElementByID("exec-dir"),
+		selectMode:     doc.ElementByID("exec-mode"),
+		inputNewlines:  doc.ElementByID("exec-newlines"),
+		inputKillDelay: doc.ElementByID("exec-killdelay"),
+	}
+
+	focusedExec *Exec
+)
+
+func init() {
+	o := &execOutlets
+	o.inputCommand.AddEventListener("change", func(dom.Object) {
+		focusedExec.Command = o.inputCommand.Get("value").String()
+	})
+	o.textareaArgs.AddEventListener("change", func(dom.Object) {
+		var args []string
+		for _, a := range strings.Split(o.textareaArgs.Get("value").String(), "\n") {
+			if a != "" {
+				args = append(args, a)
+			}
+		}
+		focusedExec.Args = args
+	})
+	o.inputDir.AddEventListener("change", func(dom.Object) {
+		focusedExec.Dir = o.inputDir.Get("value").String()
+	})
+	o.selectMode.AddEventListener("change", func(dom.Object) {
+		focusedExec.Mode = ExecMode(o.selectMode.Get("value").String())
+	})
+	o.inputNewlines.AddEventListener("change", func(dom.Object) {
+		focusedExec.Newlines = o.inputNewlines.Get("checked").Bool()
+	})
+	o.inputKillDelay.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedExec.KillDelay = t
+	}))
+}
+
+func (e *Exec) GainFocus() {
+	focusedExec = e
+	o := &execOutlets
+	o.inputCommand.Set("value", e.Command)
+	o.textareaArgs.Set("value", strings.Join(e.Args, "\n"))
+	o.inputDir.Set("value", e.Dir)
+	o.selectMode.Set("value", e.Mode)
+	o.inputNewlines.Set("checked", e.Newlines)
+	o.inputKillDelay.Set("value", e.KillDelay.String())
+}
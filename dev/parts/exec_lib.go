@@ -0,0 +1,157 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecStatus is the outcome of running a command with an Exec part.
+type ExecStatus struct {
+	// ExitCode is the exit code of the process, or -1 if it couldn't be
+	// started or was ended by a signal.
+	ExitCode int
+
+	// Err is the error from starting or waiting for the process, if any.
+	// A non-zero exit code is an *exec.ExitError.
+	Err error
+}
+
+// ExecPipes connects a command run with ExecCommand to channels. Any of the
+// channels can be nil.
+type ExecPipes struct {
+	// Each value received from Stdin is written to the standard input of
+	// the process, followed by a newline if Newlines is true. Standard
+	// input is closed when Stdin is closed, or at once if Stdin is nil.
+	Stdin    <-chan string
+	Newlines bool
+
+	// Stdout and Stderr are sent each line of the standard output and
+	// standard error of the process, without the line ending. Output
+	// is discarded if they are nil.
+	Stdout, Stderr chan<- string
+
+	// When Cancel is closed, the process group is interrupted. If it is still
+	// running after KillDelay (and KillDelay isn't zero), it is killed.
+	Cancel    <-chan struct{}
+	KillDelay time.Duration
+}
+
+// ExecCommand starts cmd in a new process group, connects it to the
+// channels in p, and waits for it to finish and for all of its output to
+// be sent. cmd must not have Stdin, Stdout, or Stderr already set.
+func ExecCommand(cmd *exec.Cmd, p ExecPipes) ExecStatus {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return ExecStatus{ExitCode: -1, Err: err}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return ExecStatus{ExitCode: -1, Err: err}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return ExecStatus{ExitCode: -1, Err: err}
+	}
+	execSetpgid(cmd)
+	if err := cmd.Start(); err != nil {
+		return ExecStatus{ExitCode: -1, Err: err}
+	}
+
+	done := make(chan struct{})
+	if p.Stdin == nil {
+		stdin.Close()
+	} else {
+		go func() {
+			defer stdin.Close()
+			for {
+				select {
+				case s, open := <-p.Stdin:
+					if !open {
+						return
+					}
+					if p.Newlines {
+						s += "\n"
+					}
+					if _, err := io.WriteString(stdin, s); err != nil {
+						// Probably the process has exited.
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		select {
+		case <-p.Cancel:
+		case <-done:
+			return
+		}
+		execInterrupt(cmd.Process)
+		if p.KillDelay == 0 {
+			return
+		}
+		t := time.NewTimer(p.KillDelay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			execKill(cmd.Process)
+		case <-done:
+		}
+	}()
+
+	// All the output has to be read before calling Wait.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go execLines(&wg, stdout, p.Stdout)
+	go execLines(&wg, stderr, p.Stderr)
+	wg.Wait()
+	err = cmd.Wait()
+	close(done)
+
+	st := ExecStatus{ExitCode: -1, Err: err}
+	if cmd.ProcessState != nil {
+		st.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return st
+}
+
+// execLines sends each line read from r to out, or discards them if out is nil.
+func execLines(wg *sync.WaitGroup, r io.Reader, out chan<- string) {
+	defer wg.Done()
+	if out == nil {
+		io.Copy(ioutil.Discard, r)
+		return
+	}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			out <- strings.TrimSuffix(line, "\r")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux darwin
+
+package parts
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecCommand(t *testing.T) {
+	stdin := make(chan string, 2)
+	stdin <- "hello"
+	stdin <- "world"
+	close(stdin)
+	stdout, stderr := make(chan string, 10), make(chan string, 10)
+	cmd := exec.Command("sh", "-c", `while read l; do echo "out $l"; done; printf 'err\r\nlast' >&2; exit 3`)
+	st := ExecCommand(cmd, ExecPipes{
+		Stdin:    stdin,
+		Newlines: true,
+		Stdout:   stdout,
+		Stderr:   stderr,
+	})
+	close(stdout)
+	close(stderr)
+	if st.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", st.ExitCode)
+	}
+	if _, ok := st.Err.(*exec.ExitError); !ok {
+		t.Errorf("Err = %v, want an *exec.ExitError", st.Err)
+	}
+	if got, want := collectStrings(stdout), []string{"out hello", "out world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if got, want := collectStrings(stderr), []string{"err", "last"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestExecCommandNotFound(t *testing.T) {
+	st := ExecCommand(exec.Command("/nonexistent/command"), ExecPipes{})
+	if st.ExitCode != -1 || st.Err == nil {
+		t.Errorf("ExecCommand() = %+v, want ExitCode -1 and an error", st)
+	}
+}
+
+func TestExecCommandCancel(t *testing.T) {
+	// The background sleep ignores SIGINT, as background jobs of
+	// non-interactive shells do, so has to be killed, and holds on to
+	// stdout until it is.
+	cmd := exec.Command("sh", "-c", "sleep 10 & wait")
+	cancel := make(chan struct{})
+	time.AfterFunc(100*time.Millisecond, func() { close(cancel) })
+	start := time.Now()
+	st := ExecCommand(cmd, ExecPipes{
+		Cancel:    cancel,
+		KillDelay: 200 * time.Millisecond,
+	})
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("ExecCommand took %v, want the process group killed sooner", d)
+	}
+	if st.ExitCode != -1 || st.Err == nil {
+		t.Errorf("ExecCommand() = %+v, want ExitCode -1 and an error", st)
+	}
+}
+
+func collectStrings(ch <-chan string) []string {
+	var s []string
+	for x := range ch {
+		s = append(s, x)
+	}
+	return s
+}
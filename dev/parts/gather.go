@@ -17,19 +17,80 @@ package parts
 import (
 	"bytes"
 	"fmt"
+	"text/template"
 
 	"github.com/google/shenzhen-go/dev/model"
 	"github.com/google/shenzhen-go/dev/model/pin"
 )
 
+// GatherMode is the order in which a Gather part sends values.
+type GatherMode string
+
+// Values for GatherMode.
+const (
+	GatherArrival GatherMode = "arrival"
+	GatherSorted  GatherMode = "sorted"
+)
+
+// gatherSortedBodyTmpl is a k-way merge. It holds the next value from
+// every open input in a heap, so the least is always at the top.
+var gatherSortedBodyTmpl = template.Must(template.New("gather-sorted-body").Parse(`
+	less := func(a, b {{.Type}}) bool {
+		in := a
+		ka := {{.Key}}
+		in = b
+		kb := {{.Key}}
+		return ka < kb
+	}
+	type gatherItem struct {
+		value {{.Type}}
+		src   <-chan {{.Type}}
+		idx   int
+	}
+	h := &parts.Heap{
+		LessFunc: func(x, y interface{}) bool {
+			a, b := x.(gatherItem), y.(gatherItem)
+			switch {
+			case less(a.value, b.value):
+				return true
+			case less(b.value, a.value):
+				return false
+			}
+			// Equal keys are sent in input order.
+			return a.idx < b.idx
+		},
+	}
+	next := func(src <-chan {{.Type}}, idx int) {
+		if in, open := <-src; open {
+			heap.Push(h, gatherItem{value: in, src: src, idx: idx})
+		}
+	}
+	{{range .Inputs -}}
+	next(input{{.}}, {{.}})
+	{{end -}}
+	for h.Len() > 0 {
+		it := heap.Pop(h).(gatherItem)
+		output <- it.value
+		next(it.src, it.idx)
+	}`))
+
 func init() {
 	model.RegisterPartType("Gather", "Flow", &model.PartType{
-		New: func() model.Part { return &Gather{InputNum: 2} },
+		New: func() model.Part { return &Gather{InputNum: 2, Mode: GatherArrival, Key: "in"} },
 		Panels: []model.PartPanel{
 			{
 				Name: "Gather",
 				Editor: `<div class="form"><div class="formfield">
 					<label>Number of inputs: <input id="gather-inputnum" type="number"></input></label>
+				</div><div class="formfield">
+					<label for="gather-mode">Mode</label>
+					<select id="gather-mode" name="gather-mode">
+						<option value="arrival" selected>Arrival order</option>
+						<option value="sorted">Sorted merge</option>
+					</select>
+				</div><div class="formfield">
+					<label for="gather-key">Sort key expression</label>
+					<input id="gather-key" name="gather-key" type="text" title="Must be a Go expression using in" value="in"></input>
 				</div></div>`,
 			},
 			{
@@ -42,6 +103,16 @@ func init() {
 				Gather is useful for combining multiple outputs. While a single channel
 				can be attached to multiple outputs, it can cause a panic if both outputs
 				try to close the channel.
+			</p><p>
+				In arrival order mode, values are sent in the order they arrive.
+				In sorted merge mode, each input must be sorted by the sort key, and
+				the output is sorted by the sort key too. The sort key expression is
+				a Go expression in terms of a value <code>in</code>, for example
+				<code>in.Timestamp.UnixNano()</code>, and must produce values that
+				can be compared with <code>&lt;</code>. Values with equal keys are
+				sent in order of input number. To do this, the part waits until it
+				has a value from every input that isn't closed, so a slow input
+				slows down the output. Multiplicity should be 1.
 			</p>
 			</div>`,
 			},
@@ -52,7 +123,9 @@ func init() {
 // Gather is a part type which reads a configurable number of inputs
 // and sends values to a single output.
 type Gather struct {
-	InputNum uint `json:"input_num"`
+	InputNum uint       `json:"input_num"`
+	Mode     GatherMode `json:"mode,omitempty"`
+	Key      string     `json:"key,omitempty"`
 }
 
 // Clone returns a clone of this part.
@@ -62,6 +135,14 @@ func (g Gather) Clone() model.Part { return g }
 // Compared with the N-goroutine approach, this doesn't require a WaitGroup
 // and has less hidden-buffer (won't read from inputs if blocked on output).
 func (g Gather) Impl(n *model.Node) model.PartImpl {
+	switch g.Mode {
+	case "", GatherArrival:
+		// Below.
+	case GatherSorted:
+		return g.sortedImpl(n)
+	default:
+		panic("unknown mode " + g.Mode)
+	}
 	lb, sb := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	lb.WriteString(`for {
 		if true `)
@@ -87,6 +168,37 @@ func (g Gather) Impl(n *model.Node) model.PartImpl {
 	}
 }
 
+func (g Gather) sortedImpl(n *model.Node) model.PartImpl {
+	params := struct {
+		Type, Key string
+		Inputs    []uint
+	}{
+		Type: n.TypeParams["$Any"].String(),
+		Key:  g.Key,
+	}
+	if params.Key == "" {
+		params.Key = "in"
+	}
+	for i := uint(0); i < g.InputNum; i++ {
+		if n.Connections[fmt.Sprintf("input%d", i)] == "nil" {
+			continue
+		}
+		params.Inputs = append(params.Inputs, i)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := gatherSortedBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute gather-sorted-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"container/heap"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: b.String(),
+		Tail: `close(output)`,
+	}
+}
+
 // Pins returns a map with N inputs and 1 output.
 func (g Gather) Pins() pin.Map {
 	m := pin.NewMap(&pin.Definition{
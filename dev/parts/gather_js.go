@@ -20,6 +20,8 @@ import "github.com/google/shenzhen-go/dev/dom"
 
 var (
 	inputGatherInputNum = doc.ElementByID("gather-inputnum")
+	selectGatherMode    = doc.ElementByID("gather-mode")
+	inputGatherKey      = doc.ElementByID("gather-key")
 	focusedGather       *Gather
 )
 
@@ -27,9 +29,17 @@ func init() {
 	inputGatherInputNum.AddEventListener("change", func(dom.Object) {
 		focusedGather.InputNum = uint(inputGatherInputNum.Get("value").Int())
 	})
+	selectGatherMode.AddEventListener("change", func(dom.Object) {
+		focusedGather.Mode = GatherMode(selectGatherMode.Get("value").String())
+	})
+	inputGatherKey.AddEventListener("change", func(dom.Object) {
+		focusedGather.Key = inputGatherKey.Get("value").String()
+	})
 }
 
 func (g *Gather) GainFocus() {
 	focusedGather = g
 	inputGatherInputNum.Set("value", g.InputNum)
+	selectGatherMode.Set("value", g.Mode)
+	inputGatherKey.Set("value", g.Key)
 }
@@ -0,0 +1,61 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/shenzhen-go/dev/model"
+)
+
+// goRunGraph generates the Go for a command graph (in JSON), runs it, and
+// returns the output. The graph must only import the standard library.
+// The test is skipped if the go tool isn't available.
+func goRunGraph(t *testing.T, graphJSON string) string {
+	t.Helper()
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("LookPath(go) = error %v", err)
+	}
+	g, err := model.LoadJSON(strings.NewReader(graphJSON), "test.szgo", "test.szgo")
+	if err != nil {
+		t.Fatalf("LoadJSON() = error %v", err)
+	}
+	src, err := g.Go()
+	if err != nil {
+		t.Fatalf("Go() = error %v", err)
+	}
+	dir, err := ioutil.TempDir("", g.Name)
+	if err != nil {
+		t.Fatalf("TempDir() = error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("WriteFile() = error %v", err)
+	}
+	cmd := exec.Command(goTool, "run", "main.go")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run = error %v, output:\n%s", err, out)
+	}
+	return string(out)
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+// Heap is a binary heap of arbitrary items, ordered by LessFunc. It
+// implements heap.Interface, and is intended for use by generated code
+// with the functions in container/heap.
+type Heap struct {
+	Items    []interface{}
+	LessFunc func(a, b interface{}) bool
+
+	// IndexFunc, if not nil, is called whenever an item moves to a new
+	// index, and with index -1 when an item is popped. Tracking indexes
+	// allows items to be updated or removed with heap.Fix or heap.Remove.
+	IndexFunc func(x interface{}, i int)
+}
+
+// Len returns the number of items.
+func (h *Heap) Len() int { return len(h.Items) }
+
+// Less reports whether item i is ordered before item j.
+func (h *Heap) Less(i, j int) bool { return h.LessFunc(h.Items[i], h.Items[j]) }
+
+// Swap swaps items i and j.
+func (h *Heap) Swap(i, j int) {
+	h.Items[i], h.Items[j] = h.Items[j], h.Items[i]
+	if h.IndexFunc != nil {
+		h.IndexFunc(h.Items[i], i)
+		h.IndexFunc(h.Items[j], j)
+	}
+}
+
+// Push appends an item. Use heap.Push instead.
+func (h *Heap) Push(x interface{}) {
+	if h.IndexFunc != nil {
+		h.IndexFunc(x, len(h.Items))
+	}
+	h.Items = append(h.Items, x)
+}
+
+// Pop removes the last item. Use heap.Pop instead.
+func (h *Heap) Pop() interface{} {
+	n := len(h.Items) - 1
+	x := h.Items[n]
+	h.Items[n] = nil
+	h.Items = h.Items[:n]
+	if h.IndexFunc != nil {
+		h.IndexFunc(x, -1)
+	}
+	return x
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"container/heap"
+	"testing"
+)
+
+func TestHeap(t *testing.T) {
+	type item struct{ value, index int }
+	h := &Heap{
+		LessFunc:  func(a, b interface{}) bool { return a.(*item).value < b.(*item).value },
+		IndexFunc: func(x interface{}, i int) { x.(*item).index = i },
+	}
+	items := make(map[int]*item)
+	for _, v := range []int{5, 3, 8, 1, 9, 2, 7} {
+		it := &item{value: v}
+		items[v] = it
+		heap.Push(h, it)
+	}
+	for _, it := range items {
+		if got := h.Items[it.index]; got != it {
+			t.Fatalf("h.Items[%d] = %v, want %v", it.index, got, it)
+		}
+	}
+
+	// Remove 8, and change 9 into 0.
+	heap.Remove(h, items[8].index)
+	items[9].value = 0
+	heap.Fix(h, items[9].index)
+
+	want := []int{0, 1, 2, 3, 5, 7}
+	for _, w := range want {
+		it := heap.Pop(h).(*item)
+		if it.value != w {
+			t.Errorf("heap.Pop(h) = %d, want %d", it.value, w)
+		}
+		if it.index != -1 {
+			t.Errorf("popped item index = %d, want -1", it.index)
+		}
+	}
+	if h.Len() != 0 {
+		t.Errorf("h.Len() = %d, want 0", h.Len())
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const httpClientCtxTypeParam = "$Ctx"
+
+func httpClientRequestType(ct string) string {
+	return fmt.Sprintf("struct{ Method, URL string; Header http.Header; Body []byte; Ctx %s }", ct)
+}
+
+func httpClientResponseType(ct string) string {
+	return fmt.Sprintf("struct{ Status int; Header http.Header; Body []byte; Ctx %s }", ct)
+}
+
+func httpClientErrorType(ct string) string {
+	return fmt.Sprintf("struct{ Err error; Ctx %s }", ct)
+}
+
+var (
+	httpClientPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "requests",
+			Direction: pin.Input,
+			Type:      httpClientRequestType(httpClientCtxTypeParam),
+		},
+		&pin.Definition{
+			Name:      "responses",
+			Direction: pin.Output,
+			Type:      httpClientResponseType(httpClientCtxTypeParam),
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      httpClientErrorType(httpClientCtxTypeParam),
+		},
+	)
+
+	httpClientHeadTmpl = template.Must(template.New("httpclient-head").Parse(`
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   {{.DialTimeoutNanos}}, // {{.DialTimeout}}
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ResponseHeaderTimeout: {{.ResponseHeaderTimeoutNanos}}, // {{.ResponseHeaderTimeout}}
+		MaxIdleConnsPerHost:   multiplicity,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	var roundTripper http.RoundTripper = transport
+	{{if .Prometheus -}}
+	roundTripper = promhttp.InstrumentRoundTripperDuration(
+		httpClientDuration.MustCurryWith(prometheus.Labels{"node_name": "{{.NodeName}}"}),
+		roundTripper,
+	)
+	{{end -}}
+	client := &http.Client{
+		Transport: roundTripper,
+		Timeout:   {{.TimeoutNanos}}, // {{.Timeout}}
+	}`))
+
+	httpClientBodyTmpl = template.Must(template.New("httpclient-body").Parse(`
+	do := func(req {{.RequestType}}) (resp {{.ResponseType}}, err error) {
+		resp.Ctx = req.Ctx
+		var body io.Reader
+		if req.Body != nil {
+			body = bytes.NewReader(req.Body)
+		}
+		hreq, err := http.NewRequest(req.Method, req.URL, body)
+		if err != nil {
+			return resp, err
+		}
+		for k, v := range req.Header {
+			hreq.Header[k] = v
+		}
+		hresp, err := client.Do(hreq)
+		if err != nil {
+			return resp, err
+		}
+		defer hresp.Body.Close()
+		resp.Status, resp.Header = hresp.StatusCode, hresp.Header
+		resp.Body, err = ioutil.ReadAll(hresp.Body)
+		return resp, err
+	}
+	for req := range requests {
+		resp, err := do(req)
+		if err != nil {
+			if errors != nil {
+				errors <- {{.ErrorType}}{Err: err, Ctx: req.Ctx}
+			}
+			continue
+		}
+		if responses != nil {
+			responses <- resp
+		}
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("HTTPClient", "Web", &model.PartType{
+		New: func() model.Part {
+			return &HTTPClient{
+				Timeout:               time.Minute,
+				DialTimeout:           30 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
+			}
+		},
+		Init: `
+		var httpClientDuration = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "shenzhen_go",
+				Subsystem: "http_client",
+				Name:      "request_duration_seconds",
+				Help:      "Durations of requests made by HTTPClient nodes",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"node_name", "code", "method"},
+		)
+
+		func init() {
+			prometheus.MustRegister(httpClientDuration)
+		}
+		`,
+		Panels: []model.PartPanel{
+			{
+				Name: "Client",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<input id="httpclient-enableprometheus" name="httpclient-enableprometheus" type="checkbox"></input>
+					<label for="httpclient-enableprometheus">Enable Prometheus metrics</label>
+				</div>
+				<div class="formfield">
+					<label for="httpclient-timeout">Timeout</label>
+					<input id="httpclient-timeout" name="httpclient-timeout" type="text" required title="Must be a parseable time.Duration" value="1m"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpclient-dialtimeout">Dial timeout</label>
+					<input id="httpclient-dialtimeout" name="httpclient-dialtimeout" type="text" required title="Must be a parseable time.Duration" value="30s"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpclient-responseheadertimeout">Response header timeout</label>
+					<input id="httpclient-responseheadertimeout" name="httpclient-responseheadertimeout" type="text" required title="Must be a parseable time.Duration" value="30s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPClient part makes HTTP requests. Each request received is a
+				struct with the method, URL, headers, and body of the request to make,
+				and a Ctx value of any type, which is passed through to the response
+				or error. An empty method means GET, and a nil body means no body.
+			</p><p>
+				For each successful request, the status code, headers, and body
+				of the response are sent to responses. A status code of 4xx or 5xx
+				is still a successful request. If the request fails, for example
+				because the server couldn't be reached, the error is sent to errors.
+			</p><p>
+				The timeout limits the whole time taken by a request, including
+				reading the response body. The dial timeout limits the time taken to
+				connect to the server, and the response header timeout limits the time
+				waiting for the response header after sending the request. Zero means
+				no limit.
+			</p><p>
+				Multiplicity limits the number of requests made at once.
+			</p><p>
+				With Prometheus metrics enabled, a histogram of request durations is
+				exported, labelled with the method and response code.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// HTTPClient is a part which makes HTTP requests.
+type HTTPClient struct {
+	EnablePrometheus      bool          `json:"enable_prometheus"`
+	Timeout               time.Duration `json:"timeout,omitempty"`
+	DialTimeout           time.Duration `json:"dial_timeout,omitempty"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+}
+
+// Clone returns a clone of this HTTPClient.
+func (c *HTTPClient) Clone() model.Part {
+	c0 := *c
+	return &c0
+}
+
+// Impl returns the HTTPClient implementation.
+func (c *HTTPClient) Impl(n *model.Node) model.PartImpl {
+	ct := n.TypeParams[httpClientCtxTypeParam].String()
+	params := struct {
+		RequestType, ResponseType, ErrorType string
+
+		Timeout, DialTimeout, ResponseHeaderTimeout                time.Duration
+		TimeoutNanos, DialTimeoutNanos, ResponseHeaderTimeoutNanos int64
+
+		Prometheus bool
+		NodeName   string
+	}{
+		RequestType:                httpClientRequestType(ct),
+		ResponseType:               httpClientResponseType(ct),
+		ErrorType:                  httpClientErrorType(ct),
+		Timeout:                    c.Timeout,
+		DialTimeout:                c.DialTimeout,
+		ResponseHeaderTimeout:      c.ResponseHeaderTimeout,
+		TimeoutNanos:               int64(c.Timeout),
+		DialTimeoutNanos:           int64(c.DialTimeout),
+		ResponseHeaderTimeoutNanos: int64(c.ResponseHeaderTimeout),
+		Prometheus:                 c.EnablePrometheus,
+		NodeName:                   n.Name,
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := httpClientHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute httpclient-head template: " + err.Error())
+	}
+	if err := httpClientBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute httpclient-body template: " + err.Error())
+	}
+	imps := []string{
+		`"bytes"`,
+		`"io"`,
+		`"io/ioutil"`,
+		`"net"`,
+		`"net/http"`,
+		`"time"`,
+	}
+	if c.EnablePrometheus {
+		imps = append(imps,
+			`"github.com/prometheus/client_golang/prometheus"`,
+			`"github.com/prometheus/client_golang/prometheus/promhttp"`,
+		)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `transport.CloseIdleConnections()
+		if responses != nil {
+			close(responses)
+		}
+		if errors != nil {
+			close(errors)
+		}`,
+		NeedsInit: c.EnablePrometheus,
+	}
+}
+
+// Pins returns a map declaring a request input, and outputs for responses
+// and errors.
+func (c *HTTPClient) Pins() pin.Map { return httpClientPins }
+
+// TypeKey returns "HTTPClient".
+func (c *HTTPClient) TypeKey() string { return "HTTPClient" }
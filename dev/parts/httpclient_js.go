@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	httpClientOutlets = struct {
+		inputEnablePrometheus      dom.Element
+		inputTimeout               dom.Element
+		inputDialTimeout           dom.Element
+		inputResponseHeaderTimeout dom.Element
+	}{
+		inputEnablePrometheus:      doc.ElementByID("httpclient-enableprometheus"),
+		inputTimeout:               doc.ElementByID("httpclient-timeout"),
+		inputDialTimeout:           doc.ElementByID("httpclient-dialtimeout"),
+		inputResponseHeaderTimeout: doc.ElementByID("httpclient-responseheadertimeout"),
+	}
+
+	focusedHTTPClient *HTTPClient
+)
+
+func init() {
+	o := &httpClientOutlets
+	o.inputEnablePrometheus.AddEventListener("change", func(dom.Object) {
+		focusedHTTPClient.EnablePrometheus = o.inputEnablePrometheus.Get("checked").Bool()
+	})
+	o.inputTimeout.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedHTTPClient.Timeout = t
+	}))
+	o.inputDialTimeout.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedHTTPClient.DialTimeout = t
+	}))
+	o.inputResponseHeaderTimeout.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedHTTPClient.ResponseHeaderTimeout = t
+	}))
+}
+
+func (c *HTTPClient) GainFocus() {
+	focusedHTTPClient = c
+	o := &httpClientOutlets
+	o.inputEnablePrometheus.Set("checked", c.EnablePrometheus)
+	o.inputTimeout.Set("value", c.Timeout.String())
+	o.inputDialTimeout.Set("value", c.DialTimeout.String())
+	o.inputResponseHeaderTimeout.Set("value", c.ResponseHeaderTimeout.String())
+}
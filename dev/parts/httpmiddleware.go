@@ -0,0 +1,515 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+// HTTPAuthMode is the authentication scheme used by a HTTPAuth part.
+type HTTPAuthMode string
+
+// Values for HTTPAuthMode.
+const (
+	HTTPAuthBasic  HTTPAuthMode = "basic"
+	HTTPAuthBearer HTTPAuthMode = "bearer"
+)
+
+var (
+	httpMiddlewarePins = pin.NewMap(
+		&pin.Definition{
+			Name:      "in",
+			Direction: pin.Input,
+			Type:      "*parts.HTTPRequest",
+		},
+		&pin.Definition{
+			Name:      "out",
+			Direction: pin.Output,
+			Type:      "*parts.HTTPRequest",
+		},
+	)
+
+	httpAuthBodyTmpl = template.Must(template.New("httpauth-body").Parse(`
+	for r := range in {
+		{{if eq .Mode "basic" -}}
+		user, pass, ok := r.Request.BasicAuth()
+		if ok {
+			want, found := credentials[user]
+			ok = found && subtle.ConstantTimeCompare([]byte(pass), []byte(want)) == 1
+		}
+		{{- else -}}
+		ok := false
+		if auth := r.Request.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			token := []byte(auth[7:])
+			for t := range credentials {
+				if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+					ok = true
+				}
+			}
+		}
+		{{- end}}
+		if !ok {
+			r.Header().Set("WWW-Authenticate", {{printf "%q" .Challenge}})
+			http.Error(r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			r.Close()
+			continue
+		}
+		out <- r
+	}`))
+
+	httpCORSBodyTmpl = template.Must(template.New("httpcors-body").Parse(`
+	for r := range in {
+		origin := r.Request.Header.Get("Origin")
+		{{if not .AnyOrigin -}}
+		if !allowedOrigins[origin] {
+			origin = ""
+		}
+		{{end -}}
+		if origin == "" {
+			out <- r
+			continue
+		}
+		h := r.Header()
+		{{if and .AnyOrigin (not .AllowCredentials) -}}
+		h.Set("Access-Control-Allow-Origin", "*")
+		{{- else -}}
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		{{- end}}
+		{{if .AllowCredentials -}}
+		h.Set("Access-Control-Allow-Credentials", "true")
+		{{end -}}
+		{{if .ExposedHeaders -}}
+		h.Set("Access-Control-Expose-Headers", {{printf "%q" .ExposedHeaders}})
+		{{end -}}
+		if r.Request.Method != http.MethodOptions || r.Request.Header.Get("Access-Control-Request-Method") == "" {
+			out <- r
+			continue
+		}
+		// Preflight request.
+		h.Del("Access-Control-Expose-Headers")
+		h.Set("Access-Control-Allow-Methods", {{printf "%q" .AllowedMethods}})
+		{{if .AllowedHeaders -}}
+		h.Set("Access-Control-Allow-Headers", {{printf "%q" .AllowedHeaders}})
+		{{end -}}
+		{{if .MaxAge -}}
+		h.Set("Access-Control-Max-Age", "{{.MaxAge}}")
+		{{end -}}
+		r.WriteHeader(http.StatusNoContent)
+		r.Close()
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("HTTPAccessLog", "Web", &model.PartType{
+		New: func() model.Part { return &HTTPAccessLog{} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Log",
+				Editor: `<div class="form"><div class="formfield">
+					<input id="httpaccesslog-combined" name="httpaccesslog-combined" type="checkbox"></input>
+					<label for="httpaccesslog-combined">Combined Log Format</label>
+				</div></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPAccessLog part passes requests from in to out, and writes a line
+				to standard error for each response, in Common Log Format (or Combined
+				Log Format, which adds the referer and user agent).
+			</p><p>
+				Each instance waits for the request to be closed downstream before
+				logging it, so multiplicity limits the number of requests that can
+				be handled at once.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPAuth", "Web", &model.PartType{
+		New: func() model.Part {
+			return &HTTPAuth{
+				Mode:        HTTPAuthBasic,
+				Realm:       "Restricted",
+				Credentials: map[string]string{},
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Auth",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpauth-mode">Mode</label>
+					<select id="httpauth-mode" name="httpauth-mode">
+						<option value="basic" selected>Basic (username and password)</option>
+						<option value="bearer">Bearer token</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="httpauth-realm">Realm</label>
+					<input id="httpauth-realm" name="httpauth-realm" type="text"></input>
+				</div>
+			</div>
+			<div class="codeedit" id="httpauth-credentials"></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPAuth part checks the credentials of each request. Requests with
+				valid credentials are passed from in to out unchanged. Other requests
+				are responded to with a 401 (Unauthorized) error by this part, with a
+				<code>WWW-Authenticate</code> header for the realm.
+			</p><p>
+				The credentials are a JSON object. In basic mode, it maps usernames
+				to passwords. In bearer mode, the keys are the valid tokens (and the
+				values are ignored, so can describe who has each token).
+			</p><p>
+				The credentials are stored in the graph and the generated program,
+				so they should be protected like any other secret.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPGzip", "Web", &model.PartType{
+		New: func() model.Part { return &HTTPGzip{Level: gzip.DefaultCompression} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Gzip",
+				Editor: `<div class="form"><div class="formfield">
+					<label for="httpgzip-level">Compression level</label>
+					<input id="httpgzip-level" name="httpgzip-level" type="number" required min="-2" max="9" value="-1" title="-1 is the default level, 1 is fastest, 9 is best compression"></input>
+				</div></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPGzip part compresses responses with gzip, by wrapping the
+				<code>ResponseWriter</code> of requests passed from in to out. Requests
+				from clients that don't accept gzip are passed on unchanged.
+			</p><p>
+				Responses that already have a <code>Content-Encoding</code>, or have no
+				body, are not compressed.
+			</p><p>
+				The level is as in <code>compress/gzip</code>: -1 is the default,
+				1 is fastest, and 9 is best compression.
+			</p><p>
+				Each instance waits for a compressed response to be closed downstream,
+				so multiplicity limits the number of requests that can be compressed at once.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPCORS", "Web", &model.PartType{
+		New: func() model.Part {
+			return &HTTPCORS{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "HEAD", "POST"},
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "CORS",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpcors-allowedorigins">Allowed origins</label>
+					<input id="httpcors-allowedorigins" name="httpcors-allowedorigins" type="text" title="Comma-separated list of origins, or *"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpcors-allowedmethods">Allowed methods</label>
+					<input id="httpcors-allowedmethods" name="httpcors-allowedmethods" type="text" title="Comma-separated list of methods"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpcors-allowedheaders">Allowed headers</label>
+					<input id="httpcors-allowedheaders" name="httpcors-allowedheaders" type="text" title="Comma-separated list of request headers"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpcors-exposedheaders">Exposed headers</label>
+					<input id="httpcors-exposedheaders" name="httpcors-exposedheaders" type="text" title="Comma-separated list of response headers"></input>
+				</div>
+				<div class="formfield">
+					<input id="httpcors-allowcredentials" name="httpcors-allowcredentials" type="checkbox"></input>
+					<label for="httpcors-allowcredentials">Allow credentials</label>
+				</div>
+				<div class="formfield">
+					<label for="httpcors-maxage">Preflight max age</label>
+					<input id="httpcors-maxage" name="httpcors-maxage" type="text" required title="Must be a parseable time.Duration" value="0s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPCORS part adds Cross-Origin Resource Sharing headers to responses
+				to requests from allowed origins, and passes the requests from in to out.
+				An allowed origin of <code>*</code> allows every origin.
+			</p><p>
+				Preflight requests (<code>OPTIONS</code> requests with an
+				<code>Access-Control-Request-Method</code> header) from allowed origins
+				are responded to by this part, with the allowed methods and headers,
+				and are not passed on. The max age, if not zero, is how long browsers
+				may cache the preflight response.
+			</p><p>
+				Requests without an <code>Origin</code> header, or from origins that
+				aren't allowed, are passed on unchanged.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// HTTPAccessLog is a part which logs requests passing through it.
+type HTTPAccessLog struct {
+	Combined bool `json:"combined,omitempty"`
+}
+
+// Clone returns a clone of this HTTPAccessLog.
+func (a *HTTPAccessLog) Clone() model.Part {
+	a0 := *a
+	return &a0
+}
+
+// Impl returns the HTTPAccessLog implementation.
+func (a *HTTPAccessLog) Impl(*model.Node) model.PartImpl {
+	return model.PartImpl{
+		Imports: []string{
+			`"log"`,
+			`"net/http"`,
+			`"os"`,
+			`"time"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Head: `logger := log.New(os.Stderr, "", 0)`,
+		Body: fmt.Sprintf(`for r := range in {
+			start := time.Now()
+			w := &parts.HTTPResponseRecorder{ResponseWriter: r.ResponseWriter}
+			r.Forward(parts.HTTPHandler(out), w)
+			if w.Status == 0 {
+				w.Status = http.StatusOK
+			}
+			logger.Print(parts.HTTPAccessLogLine(r.Request, start, w.Status, w.Size, %t))
+			r.Close()
+		}`, a.Combined),
+		Tail: `close(out)`,
+	}
+}
+
+// Pins returns a map declaring a request input and output.
+func (a *HTTPAccessLog) Pins() pin.Map { return httpMiddlewarePins }
+
+// TypeKey returns "HTTPAccessLog".
+func (a *HTTPAccessLog) TypeKey() string { return "HTTPAccessLog" }
+
+// HTTPAuth is a part which only passes on requests with valid credentials.
+type HTTPAuth struct {
+	Mode        HTTPAuthMode      `json:"mode"`
+	Realm       string            `json:"realm,omitempty"`
+	Credentials map[string]string `json:"credentials"`
+}
+
+// Clone returns a clone of this HTTPAuth.
+func (a *HTTPAuth) Clone() model.Part {
+	c := make(map[string]string, len(a.Credentials))
+	for k, v := range a.Credentials {
+		c[k] = v
+	}
+	return &HTTPAuth{
+		Mode:        a.Mode,
+		Realm:       a.Realm,
+		Credentials: c,
+	}
+}
+
+// Impl returns the HTTPAuth implementation.
+func (a *HTTPAuth) Impl(*model.Node) model.PartImpl {
+	imps := []string{
+		`"crypto/subtle"`,
+		`"net/http"`,
+		`"github.com/google/shenzhen-go/dev/parts"`,
+	}
+	var scheme string
+	switch a.Mode {
+	case HTTPAuthBasic:
+		scheme = "Basic"
+	case HTTPAuthBearer:
+		scheme = "Bearer"
+		imps = append(imps, `"strings"`)
+	default:
+		panic("unknown mode " + a.Mode)
+	}
+
+	// Write the credentials in a stable order.
+	keys := make([]string, 0, len(a.Credentials))
+	for k := range a.Credentials {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := bytes.NewBufferString("credentials := map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(h, "%q: %q,\n", k, a.Credentials[k])
+	}
+	h.WriteString("}\n")
+
+	params := struct {
+		Mode      HTTPAuthMode
+		Challenge string
+	}{
+		Mode:      a.Mode,
+		Challenge: fmt.Sprintf("%s realm=%q", scheme, a.Realm),
+	}
+	b := bytes.NewBuffer(nil)
+	if err := httpAuthBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute httpauth-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail:    `close(out)`,
+	}
+}
+
+// Pins returns a map declaring a request input and output.
+func (a *HTTPAuth) Pins() pin.Map { return httpMiddlewarePins }
+
+// TypeKey returns "HTTPAuth".
+func (a *HTTPAuth) TypeKey() string { return "HTTPAuth" }
+
+// HTTPGzip is a part which compresses responses.
+type HTTPGzip struct {
+	Level int `json:"level"`
+}
+
+// Clone returns a clone of this HTTPGzip.
+func (g *HTTPGzip) Clone() model.Part {
+	g0 := *g
+	return &g0
+}
+
+// Impl returns the HTTPGzip implementation.
+func (g *HTTPGzip) Impl(*model.Node) model.PartImpl {
+	return model.PartImpl{
+		Imports: []string{
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: fmt.Sprintf(`for r := range in {
+			if !parts.AcceptsGzip(r.Request) {
+				out <- r
+				continue
+			}
+			w := &parts.GzipResponseWriter{ResponseWriter: r.ResponseWriter, Level: %d}
+			r.Forward(parts.HTTPHandler(out), w)
+			w.Close()
+			r.Close()
+		}`, g.Level),
+		Tail: `close(out)`,
+	}
+}
+
+// Pins returns a map declaring a request input and output.
+func (g *HTTPGzip) Pins() pin.Map { return httpMiddlewarePins }
+
+// TypeKey returns "HTTPGzip".
+func (g *HTTPGzip) TypeKey() string { return "HTTPGzip" }
+
+// HTTPCORS is a part which adds CORS headers to responses, and responds to
+// preflight requests.
+type HTTPCORS struct {
+	AllowedOrigins   []string      `json:"allowed_origins"`
+	AllowedMethods   []string      `json:"allowed_methods"`
+	AllowedHeaders   []string      `json:"allowed_headers,omitempty"`
+	ExposedHeaders   []string      `json:"exposed_headers,omitempty"`
+	AllowCredentials bool          `json:"allow_credentials,omitempty"`
+	MaxAge           time.Duration `json:"max_age,omitempty"`
+}
+
+// Clone returns a clone of this HTTPCORS.
+func (c *HTTPCORS) Clone() model.Part {
+	return &HTTPCORS{
+		AllowedOrigins:   append([]string(nil), c.AllowedOrigins...),
+		AllowedMethods:   append([]string(nil), c.AllowedMethods...),
+		AllowedHeaders:   append([]string(nil), c.AllowedHeaders...),
+		ExposedHeaders:   append([]string(nil), c.ExposedHeaders...),
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+// Impl returns the HTTPCORS implementation.
+func (c *HTTPCORS) Impl(*model.Node) model.PartImpl {
+	h := bytes.NewBuffer(nil)
+	anyOrigin := false
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+		}
+	}
+	if !anyOrigin {
+		h.WriteString("allowedOrigins := map[string]bool{\n")
+		for _, o := range c.AllowedOrigins {
+			fmt.Fprintf(h, "%q: true,\n", o)
+		}
+		h.WriteString("}\n")
+	}
+	params := struct {
+		AnyOrigin, AllowCredentials                    bool
+		AllowedMethods, AllowedHeaders, ExposedHeaders string
+		MaxAge                                         int
+	}{
+		AnyOrigin:        anyOrigin,
+		AllowCredentials: c.AllowCredentials,
+		AllowedMethods:   strings.Join(c.AllowedMethods, ", "),
+		AllowedHeaders:   strings.Join(c.AllowedHeaders, ", "),
+		ExposedHeaders:   strings.Join(c.ExposedHeaders, ", "),
+		MaxAge:           int(c.MaxAge / time.Second),
+	}
+	b := bytes.NewBuffer(nil)
+	if err := httpCORSBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute httpcors-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"net/http"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Head: h.String(),
+		Body: b.String(),
+		Tail: `close(out)`,
+	}
+}
+
+// Pins returns a map declaring a request input and output.
+func (c *HTTPCORS) Pins() pin.Map { return httpMiddlewarePins }
+
+// TypeKey returns "HTTPCORS".
+func (c *HTTPCORS) TypeKey() string { return "HTTPCORS" }
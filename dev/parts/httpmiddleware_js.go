@@ -0,0 +1,147 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	inputHTTPAccessLogCombined = doc.ElementByID("httpaccesslog-combined")
+
+	selectHTTPAuthMode         = doc.ElementByID("httpauth-mode")
+	inputHTTPAuthRealm         = doc.ElementByID("httpauth-realm")
+	httpAuthCredentialsSession *dom.AceSession
+
+	inputHTTPGzipLevel = doc.ElementByID("httpgzip-level")
+
+	httpCORSOutlets = struct {
+		inputAllowedOrigins   dom.Element
+		inputAllowedMethods   dom.Element
+		inputAllowedHeaders   dom.Element
+		inputExposedHeaders   dom.Element
+		inputAllowCredentials dom.Element
+		inputMaxAge           dom.Element
+	}{
+		inputAllowedOrigins:   doc.ElementByID("httpcors-allowedorigins"),
+		inputAllowedMethods:   doc.ElementByID("httpcors-allowedmethods"),
+		inputAllowedHeaders:   doc.ElementByID("httpcors-allowedheaders"),
+		inputExposedHeaders:   doc.ElementByID("httpcors-exposedheaders"),
+		inputAllowCredentials: doc.ElementByID("httpcors-allowcredentials"),
+		inputMaxAge:           doc.ElementByID("httpcors-maxage"),
+	}
+
+	focusedHTTPAccessLog *HTTPAccessLog
+	focusedHTTPAuth      *HTTPAuth
+	focusedHTTPGzip      *HTTPGzip
+	focusedHTTPCORS      *HTTPCORS
+)
+
+// splitHTTPList splits a comma-separated list, such as "GET, POST".
+func splitHTTPList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+func init() {
+	inputHTTPAccessLogCombined.AddEventListener("change", func(dom.Object) {
+		focusedHTTPAccessLog.Combined = inputHTTPAccessLogCombined.Get("checked").Bool()
+	})
+
+	selectHTTPAuthMode.AddEventListener("change", func(dom.Object) {
+		focusedHTTPAuth.Mode = HTTPAuthMode(selectHTTPAuthMode.Get("value").String())
+	})
+	inputHTTPAuthRealm.AddEventListener("change", func(dom.Object) {
+		focusedHTTPAuth.Realm = inputHTTPAuthRealm.Get("value").String()
+	})
+	httpAuthCredentialsSession = setupAce("httpauth-credentials", dom.AceJSONMode, httpAuthCredentialsChange)
+
+	inputHTTPGzipLevel.AddEventListener("change", func(dom.Object) {
+		focusedHTTPGzip.Level = inputHTTPGzipLevel.Get("value").Int()
+	})
+
+	o := &httpCORSOutlets
+	o.inputAllowedOrigins.AddEventListener("change", func(dom.Object) {
+		focusedHTTPCORS.AllowedOrigins = splitHTTPList(o.inputAllowedOrigins.Get("value").String())
+	})
+	o.inputAllowedMethods.AddEventListener("change", func(dom.Object) {
+		focusedHTTPCORS.AllowedMethods = splitHTTPList(o.inputAllowedMethods.Get("value").String())
+	})
+	o.inputAllowedHeaders.AddEventListener("change", func(dom.Object) {
+		focusedHTTPCORS.AllowedHeaders = splitHTTPList(o.inputAllowedHeaders.Get("value").String())
+	})
+	o.inputExposedHeaders.AddEventListener("change", func(dom.Object) {
+		focusedHTTPCORS.ExposedHeaders = splitHTTPList(o.inputExposedHeaders.Get("value").String())
+	})
+	o.inputAllowCredentials.AddEventListener("change", func(dom.Object) {
+		focusedHTTPCORS.AllowCredentials = o.inputAllowCredentials.Get("checked").Bool()
+	})
+	o.inputMaxAge.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedHTTPCORS.MaxAge = t
+	}))
+}
+
+func httpAuthCredentialsChange(dom.Object) {
+	creds := make(map[string]string)
+	if err := json.Unmarshal([]byte(httpAuthCredentialsSession.Value()), &creds); err != nil {
+		log.Printf("Couldn't unmarshal httpAuthCredentialsSession value into a map[string]string: %v", err)
+		return
+	}
+	focusedHTTPAuth.Credentials = creds
+}
+
+func (a *HTTPAccessLog) GainFocus() {
+	focusedHTTPAccessLog = a
+	inputHTTPAccessLogCombined.Set("checked", a.Combined)
+}
+
+func (a *HTTPAuth) GainFocus() {
+	focusedHTTPAuth = a
+	selectHTTPAuthMode.Set("value", a.Mode)
+	inputHTTPAuthRealm.Set("value", a.Realm)
+	creds, err := json.MarshalIndent(a.Credentials, "", "\t")
+	if err != nil {
+		log.Fatalf("Couldn't marshal a map[string]string to JSON: %v", err)
+	}
+	httpAuthCredentialsSession.SetValue(string(creds))
+}
+
+func (g *HTTPGzip) GainFocus() {
+	focusedHTTPGzip = g
+	inputHTTPGzipLevel.Set("value", g.Level)
+}
+
+func (c *HTTPCORS) GainFocus() {
+	focusedHTTPCORS = c
+	o := &httpCORSOutlets
+	o.inputAllowedOrigins.Set("value", strings.Join(c.AllowedOrigins, ", "))
+	o.inputAllowedMethods.Set("value", strings.Join(c.AllowedMethods, ", "))
+	o.inputAllowedHeaders.Set("value", strings.Join(c.AllowedHeaders, ", "))
+	o.inputExposedHeaders.Set("value", strings.Join(c.ExposedHeaders, ", "))
+	o.inputAllowCredentials.Set("checked", c.AllowCredentials)
+	o.inputMaxAge.Set("value", c.MaxAge.String())
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPResponseRecorder is a http.ResponseWriter that records the status
+// code and the number of bytes of the response body, while passing them on
+// to another ResponseWriter.
+type HTTPResponseRecorder struct {
+	http.ResponseWriter
+	Status int
+	Size   int64
+}
+
+// WriteHeader records the status code and passes it on.
+func (w *HTTPResponseRecorder) WriteHeader(code int) {
+	if w.Status == 0 {
+		w.Status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the length of b and passes it on.
+func (w *HTTPResponseRecorder) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Size += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying ResponseWriter, if it supports flushing.
+func (w *HTTPResponseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HTTPAccessLogLine formats a line for an access log in Common Log Format,
+// or Combined Log Format (which adds the referer and user agent) if
+// combined is true. It doesn't end with a newline.
+func HTTPAccessLogLine(r *http.Request, start time.Time, status int, size int64, combined bool) string {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+	sz := "-"
+	if size > 0 {
+		sz = strconv.FormatInt(size, 10)
+	}
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s",
+		host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.RequestURI+" "+r.Proto, status, sz)
+	if combined {
+		line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+	}
+	return line
+}
+
+// AcceptsGzip reports whether the Accept-Encoding header of the request
+// allows a gzip-encoded response.
+func AcceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params := enc, ""
+		if i := strings.Index(enc, ";"); i >= 0 {
+			name, params = enc[:i], enc[i+1:]
+		}
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, "gzip") && name != "*" {
+			continue
+		}
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(params[2:], 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// GzipResponseWriter is a http.ResponseWriter that compresses the response
+// body with gzip, while passing it on to another ResponseWriter.
+// Responses that already have a Content-Encoding, or that have no body
+// (by status code), are passed on unchanged. It must be closed after the
+// response is complete.
+type GzipResponseWriter struct {
+	http.ResponseWriter
+	Level int // as in compress/gzip, e.g. gzip.DefaultCompression
+
+	gz          *gzip.Writer
+	wroteHeader bool
+	passthrough bool
+}
+
+// WriteHeader adjusts the header for compression, if the response will be
+// compressed, and passes on the status code.
+func (w *GzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	switch {
+	case h.Get("Content-Encoding") != "",
+		code < http.StatusOK,
+		code == http.StatusNoContent,
+		code == http.StatusNotModified:
+		w.passthrough = true
+	default:
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write compresses b and passes it on.
+func (w *GzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		// Sniff the content type before it is obscured by compression.
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if err := w.init(); err != nil {
+		return 0, err
+	}
+	return w.gz.Write(b)
+}
+
+func (w *GzipResponseWriter) init() error {
+	if w.gz != nil {
+		return nil
+	}
+	gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.Level)
+	if err != nil {
+		return err
+	}
+	w.gz = gz
+	return nil
+}
+
+// Flush flushes any compressed data so far, and then the underlying
+// ResponseWriter, if it supports flushing.
+func (w *GzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the compressed body, if any. A compressed response with
+// nothing written still needs an empty gzip stream.
+func (w *GzipResponseWriter) Close() error {
+	if !w.wroteHeader || w.passthrough {
+		return nil
+	}
+	if err := w.init(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPResponseRecorder(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := &HTTPResponseRecorder{ResponseWriter: rr}
+	w.Write([]byte("hello, "))
+	w.Write([]byte("world"))
+	if got, want := w.Status, http.StatusOK; got != want {
+		t.Errorf("Status = %d, want %d", got, want)
+	}
+	if got, want := w.Size, int64(12); got != want {
+		t.Errorf("Size = %d, want %d", got, want)
+	}
+	if got, want := rr.Body.String(), "hello, world"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	w = &HTTPResponseRecorder{ResponseWriter: httptest.NewRecorder()}
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusOK)
+	if got, want := w.Status, http.StatusNotFound; got != want {
+		t.Errorf("Status = %d, want %d", got, want)
+	}
+}
+
+func TestHTTPAccessLogLine(t *testing.T) {
+	r := httptest.NewRequest("GET", "/a?b=c", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.SetBasicAuth("frank", "secret")
+	r.Header.Set("User-Agent", "test/1.0")
+	start := time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+
+	tests := []struct {
+		size     int64
+		combined bool
+		want     string
+	}{
+		{2326, false, `192.0.2.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a?b=c HTTP/1.1" 200 2326`},
+		{0, false, `192.0.2.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a?b=c HTTP/1.1" 200 -`},
+		{2326, true, `192.0.2.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a?b=c HTTP/1.1" 200 2326 "" "test/1.0"`},
+	}
+	for _, test := range tests {
+		if got := HTTPAccessLogLine(r, start, 200, test.size, test.combined); got != test.want {
+			t.Errorf("HTTPAccessLogLine(size %d, combined %t) = %q, want %q", test.size, test.combined, got, test.want)
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip", true},
+		{"deflate, GZIP;q=0.5", true},
+		{"br;q=1.0, gzip;q=0", false},
+		{"*", true},
+		{"deflate", false},
+		{"x-gzip", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", test.header)
+		if got := AcceptsGzip(r); got != test.want {
+			t.Errorf("AcceptsGzip(Accept-Encoding: %q) = %t, want %t", test.header, got, test.want)
+		}
+	}
+}
+
+func TestGzipResponseWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := &GzipResponseWriter{ResponseWriter: rr, Level: gzip.BestSpeed}
+	w.Write([]byte("<html>hello</html>"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if got, want := rr.Header().Get("Content-Encoding"), "gzip"; got != want {
+		t.Errorf("Content-Encoding = %q, want %q", got, want)
+	}
+	if got, want := rr.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() = %v", err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if got, want := string(body), "<html>hello</html>"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestGzipResponseWriterPassthrough(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := &GzipResponseWriter{ResponseWriter: rr, Level: gzip.BestSpeed}
+	w.WriteHeader(http.StatusNotModified)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want empty", got)
+	}
+	if got := rr.Body.Len(); got != 0 {
+		t.Errorf("body length = %d, want 0", got)
+	}
+
+	rr = httptest.NewRecorder()
+	w = &GzipResponseWriter{ResponseWriter: rr, Level: gzip.BestSpeed}
+	w.Header().Set("Content-Encoding", "br")
+	w.Write([]byte("already compressed"))
+	w.Close()
+	if got, want := rr.Body.String(), "already compressed"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+// All the responders have a single input of requests (JSON responders have
+// the request bundled with a body). They all close every request they
+// receive.
+var httpResponderPins = pin.NewMap(&pin.Definition{
+	Name:      "requests",
+	Direction: pin.Input,
+	Type:      "*parts.HTTPRequest",
+})
+
+func init() {
+	model.RegisterPartType("HTTPFileServer", "Web", &model.PartType{
+		New: func() model.Part { return &HTTPFileServer{Root: "."} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Files",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpfileserver-root">Root directory</label>
+					<input id="httpfileserver-root" name="httpfileserver-root" type="text" required value="."></input>
+				</div>
+				<div class="formfield">
+					<label for="httpfileserver-stripprefix">Strip prefix</label>
+					<input id="httpfileserver-stripprefix" name="httpfileserver-stripprefix" type="text"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPFileServer part serves files from a directory, using the handler
+				returned by <code>http.FileServer</code>.
+			</p><p>
+				The URL path of each request is used as the path of the file within the
+				root directory, after removing the strip prefix (if any). Requests with
+				paths not starting with the strip prefix get a 404 response. For example,
+				if HTTPServeMux routes <code>/static/</code> to this part, the strip prefix
+				should usually be <code>/static</code>.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPJSONResponder", "Web", &model.PartType{
+		New: func() model.Part { return &HTTPJSONResponder{Status: http.StatusOK} },
+		Panels: []model.PartPanel{
+			{
+				Name: "JSON",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpjsonresponder-status">Status code</label>
+					<input id="httpjsonresponder-status" name="httpjsonresponder-status" type="number" required value="200"></input>
+				</div>
+				<div class="formfield">
+					<input id="httpjsonresponder-indent" name="httpjsonresponder-indent" type="checkbox"></input>
+					<label for="httpjsonresponder-indent">Indent</label>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPJSONResponder part responds to each request with a value encoded
+				as JSON. The input is a struct containing the request to respond to and
+				the value, which can be of any type.
+			</p><p>
+				The response has the configured status code and a
+				<code>Content-Type</code> of <code>application/json</code>. If the value
+				can't be encoded, the response is a 500 error instead.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPStaticResponder", "Web", &model.PartType{
+		New: func() model.Part {
+			return &HTTPStaticResponder{
+				Status:      http.StatusOK,
+				ContentType: "text/plain; charset=utf-8",
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Content",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpstaticresponder-status">Status code</label>
+					<input id="httpstaticresponder-status" name="httpstaticresponder-status" type="number" required value="200"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpstaticresponder-contenttype">Content type</label>
+					<input id="httpstaticresponder-contenttype" name="httpstaticresponder-contenttype" type="text"></input>
+				</div>
+				<div class="formfield">
+					<input id="httpstaticresponder-template" name="httpstaticresponder-template" type="checkbox"></input>
+					<label for="httpstaticresponder-template">Content is a template</label>
+				</div>
+				<div class="formfield">
+					<textarea id="httpstaticresponder-content" name="httpstaticresponder-content" rows="20" cols="80"></textarea>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPStaticResponder part responds to every request with the same
+				status code, content type, and content.
+			</p><p>
+				If the content is a template, it is executed for each request with the
+				<code>*http.Request</code> as data, so it can refer to things like
+				<code>{{.URL.Path}}</code>. When the content type is <code>text/html</code>
+				the template is an <code>html/template</code>, otherwise it is a
+				<code>text/template</code>. If executing the template fails, the
+				response is a 500 error instead.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("HTTPRedirect", "Web", &model.PartType{
+		New: func() model.Part { return &HTTPRedirect{Code: http.StatusFound} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Redirect",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpredirect-url">URL</label>
+					<input id="httpredirect-url" name="httpredirect-url" type="text" required></input>
+				</div>
+				<div class="formfield">
+					<label for="httpredirect-code">Status code</label>
+					<select id="httpredirect-code" name="httpredirect-code">
+						<option value="301">301 Moved Permanently</option>
+						<option value="302" selected>302 Found</option>
+						<option value="303">303 See Other</option>
+						<option value="307">307 Temporary Redirect</option>
+						<option value="308">308 Permanent Redirect</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<input id="httpredirect-appendpath" name="httpredirect-appendpath" type="checkbox"></input>
+					<label for="httpredirect-appendpath">Append request path and query to URL</label>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A HTTPRedirect part responds to every request by redirecting it to a URL,
+				using <code>http.Redirect</code>. The URL may be relative to the request path.
+			</p><p>
+				When appending the request path, the path and query of each request
+				are appended to the URL, which is useful for redirecting whole sites
+				(e.g. from <code>http://example.com</code> to
+				<code>https://example.com</code>).
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// HTTPFileServer is a part which serves files from a directory.
+type HTTPFileServer struct {
+	Root        string `json:"root"`
+	StripPrefix string `json:"strip_prefix,omitempty"`
+}
+
+// Clone returns a clone of this HTTPFileServer.
+func (f *HTTPFileServer) Clone() model.Part {
+	f0 := *f
+	return &f0
+}
+
+// Impl returns the HTTPFileServer implementation.
+func (f *HTTPFileServer) Impl(*model.Node) model.PartImpl {
+	h := bytes.NewBuffer(nil)
+	fmt.Fprintf(h, "var handler http.Handler = http.FileServer(http.Dir(%q))\n", f.Root)
+	if f.StripPrefix != "" {
+		fmt.Fprintf(h, "handler = http.StripPrefix(%q, handler)\n", f.StripPrefix)
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"net/http"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Head: h.String(),
+		Body: `for r := range requests {
+			handler.ServeHTTP(r.ResponseWriter, r.Request)
+			r.Close()
+		}`,
+	}
+}
+
+// Pins returns a map declaring a single request input.
+func (f *HTTPFileServer) Pins() pin.Map { return httpResponderPins }
+
+// TypeKey returns "HTTPFileServer".
+func (f *HTTPFileServer) TypeKey() string { return "HTTPFileServer" }
+
+// HTTPJSONResponder is a part which responds to requests with values
+// encoded as JSON.
+type HTTPJSONResponder struct {
+	Status int  `json:"status"`
+	Indent bool `json:"indent,omitempty"`
+}
+
+// Clone returns a clone of this HTTPJSONResponder.
+func (j *HTTPJSONResponder) Clone() model.Part {
+	j0 := *j
+	return &j0
+}
+
+// Impl returns the HTTPJSONResponder implementation.
+func (j *HTTPJSONResponder) Impl(*model.Node) model.PartImpl {
+	marshal := "json.Marshal(in.Body)"
+	if j.Indent {
+		marshal = `json.MarshalIndent(in.Body, "", "\t")`
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"encoding/json"`,
+			`"net/http"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: fmt.Sprintf(`for in := range requests {
+			buf, err := %s
+			if err != nil {
+				http.Error(in.Req, err.Error(), http.StatusInternalServerError)
+				in.Req.Close()
+				continue
+			}
+			in.Req.Header().Set("Content-Type", "application/json")
+			in.Req.WriteHeader(%d)
+			in.Req.Write(buf)
+			in.Req.Close()
+		}`, marshal, j.Status),
+	}
+}
+
+// Pins returns a map declaring a single input of requests with bodies.
+func (j *HTTPJSONResponder) Pins() pin.Map {
+	return pin.NewMap(&pin.Definition{
+		Name:      "requests",
+		Direction: pin.Input,
+		Type:      "struct{ Req *parts.HTTPRequest; Body $T }",
+	})
+}
+
+// TypeKey returns "HTTPJSONResponder".
+func (j *HTTPJSONResponder) TypeKey() string { return "HTTPJSONResponder" }
+
+// HTTPStaticResponder is a part which responds to requests with fixed
+// or templated content.
+type HTTPStaticResponder struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     string `json:"content"`
+	Template    bool   `json:"template,omitempty"`
+}
+
+// Clone returns a clone of this HTTPStaticResponder.
+func (s *HTTPStaticResponder) Clone() model.Part {
+	s0 := *s
+	return &s0
+}
+
+// Impl returns the HTTPStaticResponder implementation.
+func (s *HTTPStaticResponder) Impl(n *model.Node) model.PartImpl {
+	imps := []string{
+		`"github.com/google/shenzhen-go/dev/parts"`,
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	b.WriteString("for r := range requests {\n")
+	if s.Template {
+		// Imports aren't per-node, so both template packages need names
+		// that other nodes won't be using.
+		pkg, imp := "texttemplate", `texttemplate "text/template"`
+		if strings.HasPrefix(s.ContentType, "text/html") {
+			pkg, imp = "htmltemplate", `htmltemplate "html/template"`
+		}
+		imps = append(imps, `"bytes"`, `"net/http"`, imp)
+		fmt.Fprintf(h, "tmpl := %s.Must(%[1]s.New(%q).Parse(%q))\n", pkg, n.Name, s.Content)
+		b.WriteString(`buf := bytes.NewBuffer(nil)
+		if err := tmpl.Execute(buf, r.Request); err != nil {
+			http.Error(r, err.Error(), http.StatusInternalServerError)
+			r.Close()
+			continue
+		}
+		`)
+	} else {
+		fmt.Fprintf(h, "content := []byte(%q)\n", s.Content)
+	}
+	if s.ContentType != "" {
+		fmt.Fprintf(b, "r.Header().Set(\"Content-Type\", %q)\n", s.ContentType)
+	}
+	fmt.Fprintf(b, "r.WriteHeader(%d)\n", s.Status)
+	if s.Template {
+		b.WriteString("buf.WriteTo(r)\n")
+	} else {
+		b.WriteString("r.Write(content)\n")
+	}
+	b.WriteString("r.Close()\n}")
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+	}
+}
+
+// Pins returns a map declaring a single request input.
+func (s *HTTPStaticResponder) Pins() pin.Map { return httpResponderPins }
+
+// TypeKey returns "HTTPStaticResponder".
+func (s *HTTPStaticResponder) TypeKey() string { return "HTTPStaticResponder" }
+
+// HTTPRedirect is a part which responds to requests with a redirect.
+type HTTPRedirect struct {
+	URL        string `json:"url"`
+	Code       int    `json:"code"`
+	AppendPath bool   `json:"append_path,omitempty"`
+}
+
+// Clone returns a clone of this HTTPRedirect.
+func (r *HTTPRedirect) Clone() model.Part {
+	r0 := *r
+	return &r0
+}
+
+// Impl returns the HTTPRedirect implementation.
+func (r *HTTPRedirect) Impl(*model.Node) model.PartImpl {
+	url := fmt.Sprintf("%q", r.URL)
+	if r.AppendPath {
+		url += " + r.Request.URL.RequestURI()"
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"net/http"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: fmt.Sprintf(`for r := range requests {
+			http.Redirect(r, r.Request, %s, %d)
+			r.Close()
+		}`, url, r.Code),
+	}
+}
+
+// Pins returns a map declaring a single request input.
+func (r *HTTPRedirect) Pins() pin.Map { return httpResponderPins }
+
+// TypeKey returns "HTTPRedirect".
+func (r *HTTPRedirect) TypeKey() string { return "HTTPRedirect" }
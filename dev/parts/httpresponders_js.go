@@ -0,0 +1,107 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import "github.com/google/shenzhen-go/dev/dom"
+
+var (
+	inputHTTPFileServerRoot        = doc.ElementByID("httpfileserver-root")
+	inputHTTPFileServerStripPrefix = doc.ElementByID("httpfileserver-stripprefix")
+
+	inputHTTPJSONResponderStatus = doc.ElementByID("httpjsonresponder-status")
+	inputHTTPJSONResponderIndent = doc.ElementByID("httpjsonresponder-indent")
+
+	inputHTTPStaticResponderStatus      = doc.ElementByID("httpstaticresponder-status")
+	inputHTTPStaticResponderContentType = doc.ElementByID("httpstaticresponder-contenttype")
+	inputHTTPStaticResponderTemplate    = doc.ElementByID("httpstaticresponder-template")
+	inputHTTPStaticResponderContent     = doc.ElementByID("httpstaticresponder-content")
+
+	inputHTTPRedirectURL        = doc.ElementByID("httpredirect-url")
+	selectHTTPRedirectCode      = doc.ElementByID("httpredirect-code")
+	inputHTTPRedirectAppendPath = doc.ElementByID("httpredirect-appendpath")
+
+	focusedHTTPFileServer      *HTTPFileServer
+	focusedHTTPJSONResponder   *HTTPJSONResponder
+	focusedHTTPStaticResponder *HTTPStaticResponder
+	focusedHTTPRedirect        *HTTPRedirect
+)
+
+func init() {
+	inputHTTPFileServerRoot.AddEventListener("change", func(dom.Object) {
+		focusedHTTPFileServer.Root = inputHTTPFileServerRoot.Get("value").String()
+	})
+	inputHTTPFileServerStripPrefix.AddEventListener("change", func(dom.Object) {
+		focusedHTTPFileServer.StripPrefix = inputHTTPFileServerStripPrefix.Get("value").String()
+	})
+
+	inputHTTPJSONResponderStatus.AddEventListener("change", func(dom.Object) {
+		focusedHTTPJSONResponder.Status = inputHTTPJSONResponderStatus.Get("value").Int()
+	})
+	inputHTTPJSONResponderIndent.AddEventListener("change", func(dom.Object) {
+		focusedHTTPJSONResponder.Indent = inputHTTPJSONResponderIndent.Get("checked").Bool()
+	})
+
+	inputHTTPStaticResponderStatus.AddEventListener("change", func(dom.Object) {
+		focusedHTTPStaticResponder.Status = inputHTTPStaticResponderStatus.Get("value").Int()
+	})
+	inputHTTPStaticResponderContentType.AddEventListener("change", func(dom.Object) {
+		focusedHTTPStaticResponder.ContentType = inputHTTPStaticResponderContentType.Get("value").String()
+	})
+	inputHTTPStaticResponderTemplate.AddEventListener("change", func(dom.Object) {
+		focusedHTTPStaticResponder.Template = inputHTTPStaticResponderTemplate.Get("checked").Bool()
+	})
+	inputHTTPStaticResponderContent.AddEventListener("change", func(dom.Object) {
+		focusedHTTPStaticResponder.Content = inputHTTPStaticResponderContent.Get("value").String()
+	})
+
+	inputHTTPRedirectURL.AddEventListener("change", func(dom.Object) {
+		focusedHTTPRedirect.URL = inputHTTPRedirectURL.Get("value").String()
+	})
+	selectHTTPRedirectCode.AddEventListener("change", func(dom.Object) {
+		focusedHTTPRedirect.Code = selectHTTPRedirectCode.Get("value").Int()
+	})
+	inputHTTPRedirectAppendPath.AddEventListener("change", func(dom.Object) {
+		focusedHTTPRedirect.AppendPath = inputHTTPRedirectAppendPath.Get("checked").Bool()
+	})
+}
+
+func (f *HTTPFileServer) GainFocus() {
+	focusedHTTPFileServer = f
+	inputHTTPFileServerRoot.Set("value", f.Root)
+	inputHTTPFileServerStripPrefix.Set("value", f.StripPrefix)
+}
+
+func (j *HTTPJSONResponder) GainFocus() {
+	focusedHTTPJSONResponder = j
+	inputHTTPJSONResponderStatus.Set("value", j.Status)
+	inputHTTPJSONResponderIndent.Set("checked", j.Indent)
+}
+
+func (s *HTTPStaticResponder) GainFocus() {
+	focusedHTTPStaticResponder = s
+	inputHTTPStaticResponderStatus.Set("value", s.Status)
+	inputHTTPStaticResponderContentType.Set("value", s.ContentType)
+	inputHTTPStaticResponderTemplate.Set("checked", s.Template)
+	inputHTTPStaticResponderContent.Set("value", s.Content)
+}
+
+func (r *HTTPRedirect) GainFocus() {
+	focusedHTTPRedirect = r
+	inputHTTPRedirectURL.Set("value", r.URL)
+	selectHTTPRedirectCode.Set("value", r.Code)
+	inputHTTPRedirectAppendPath.Set("checked", r.AppendPath)
+}
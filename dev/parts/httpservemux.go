@@ -17,6 +17,7 @@ package parts
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"text/template"
 
 	"github.com/google/shenzhen-go/dev/model"
@@ -36,7 +37,9 @@ import (
 // ServeMux.Handler has to reimplement the same logic.
 //
 // http.ServeMux sometimes returns handlers defined in net/http, so handle
-// those directly.
+// those directly. In router mode, HTTPRouter only returns handlers added in
+// the head, and the not found and method not allowed responses are written
+// here instead.
 var httpServeMuxBodyTmpl = template.Must(template.New("httpservemux-body").Parse(`
 {{if .Prometheus -}}
 labels := prometheus.Labels{
@@ -59,6 +62,20 @@ for req := range requests {
 		req.Close()
 		continue
 	}
+	{{if .Router -}}
+	hh, params, allow := mux.Route(req.Request)
+	if hh == nil {
+		if len(allow) > 0 {
+			req.ResponseWriter.Header().Set("Allow", strings.Join(allow, ", "))
+			http.Error(req.ResponseWriter, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		} else {
+			http.NotFound(req.ResponseWriter, req.Request)
+		}
+		req.Close()
+		continue
+	}
+	req.Params = params
+	{{else -}}
 	h, _ := mux.Handler(req.Request)
 	hh, ok := h.(parts.HTTPHandler)
 	if !ok {
@@ -67,6 +84,7 @@ for req := range requests {
 		req.Close()
 		continue
 	}
+	{{end -}}
 	{{if .Prometheus -}}
 	reqsOut.With(prometheus.Labels{"output_pin": outLabels[hh]}).Inc()
 	{{end -}}
@@ -116,6 +134,13 @@ func init() {
 				Name: "Routes",
 				Editor: `
 				<div class="form">
+					<div class="formfield">
+						<label for="httpservemux-mode">Mode</label>
+						<select id="httpservemux-mode" name="httpservemux-mode">
+							<option value="servemux" selected>http.ServeMux patterns</option>
+							<option value="router">Router patterns (method, host, parameters)</option>
+						</select>
+					</div>
 					<div class="formfield">
 						<input id="httpservemux-enableprometheus" name="httpservemux-enableprometheus" type="checkbox"></input>
 						<label for="httpservemux-enableprometheus">Enable Prometheus metrics</label>
@@ -135,6 +160,22 @@ func init() {
 						Most requests will be forwarded to the matching output. Ordinary Go ServeMuxes
 						handle some requests directly; HTTPServeMux attemps to match the same behaviour, 
 						so not every input request will be sent to an output.
+					</p><p>
+						In router mode, patterns may also match the method and host, and capture
+						path parameters. A pattern has the form <code>[METHOD ][HOST]/PATH</code>,
+						for example <code>GET /users/{id}</code> or <code>example.com/files/{path...}</code>.
+						A <code>{name}</code> segment matches any one non-empty path segment,
+						and a final <code>{name...}</code> segment matches the rest of the path.
+						As with ServeMux, a path ending in <code>/</code> matches every path
+						with that prefix, and the most specific matching pattern wins.
+						Captured parameters are in the <code>Params</code> field of the
+						<code>*parts.HTTPRequest</code>. The Shenzhen Go parts that pass requests
+						on (such as middleware parts) keep the parameters, but anything that
+						passes on a request by calling <code>ServeHTTP</code> with its
+						<code>Request</code> loses them, so use <code>Forward</code> instead.
+						Requests matching no pattern get a 404
+						response, unless some pattern would match with a different method, in
+						which case they get a 405 response.
 					</p>
 				</div>`,
 			},
@@ -142,9 +183,20 @@ func init() {
 	})
 }
 
-// HTTPServeMux is a part which routes requests using a http.ServeMux.
+// HTTPServeMuxMode selects the kind of patterns used by a HTTPServeMux.
+type HTTPServeMuxMode string
+
+// Values for HTTPServeMuxMode.
+const (
+	HTTPServeMuxStandard HTTPServeMuxMode = "servemux"
+	HTTPServeMuxRouter   HTTPServeMuxMode = "router"
+)
+
+// HTTPServeMux is a part which routes requests using a http.ServeMux,
+// or a parts.HTTPRouter in router mode.
 type HTTPServeMux struct {
-	EnablePrometheus bool `json:"enable_prometheus"`
+	EnablePrometheus bool             `json:"enable_prometheus"`
+	Mode             HTTPServeMuxMode `json:"mode,omitempty"`
 
 	// Routes is a map of patterns to output pin names.
 	Routes map[string]string `json:"routes"`
@@ -158,6 +210,7 @@ func (m *HTTPServeMux) Clone() model.Part {
 	}
 	return &HTTPServeMux{
 		EnablePrometheus: m.EnablePrometheus,
+		Mode:             m.Mode,
 		Routes:           r,
 	}
 }
@@ -168,11 +221,28 @@ func (m *HTTPServeMux) Impl(n *model.Node) model.PartImpl {
 	hb, bb, tb := bytes.NewBuffer(nil), bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	seen := source.NewStringSet()
 
-	hb.WriteString("mux := http.NewServeMux()\n")
+	router := false
+	switch m.Mode {
+	case "", HTTPServeMuxStandard:
+		hb.WriteString("mux := http.NewServeMux()\n")
+	case HTTPServeMuxRouter:
+		router = true
+		hb.WriteString("mux := parts.NewHTTPRouter()\n")
+	default:
+		panic("unknown mode " + m.Mode)
+	}
 	if m.EnablePrometheus {
 		hb.WriteString("outLabels := make(map[parts.HTTPHandler]string)\n")
 	}
-	for pat, out := range m.Routes {
+	// Sort the patterns, so the output is stable, and so HTTPRouter breaks
+	// ties between equally specific patterns consistently.
+	pats := make([]string, 0, len(m.Routes))
+	for pat := range m.Routes {
+		pats = append(pats, pat)
+	}
+	sort.Strings(pats)
+	for _, pat := range pats {
+		out := m.Routes[pat]
 		fmt.Fprintf(hb, "mux.Handle(%q, parts.HTTPHandler(%s))\n", pat, out)
 
 		if seen.Ni(out) {
@@ -188,6 +258,9 @@ func (m *HTTPServeMux) Impl(n *model.Node) model.PartImpl {
 		`"net/http"`,
 		`"github.com/google/shenzhen-go/dev/parts"`,
 	}
+	if router {
+		imps = append(imps, `"strings"`)
+	}
 	if m.EnablePrometheus {
 		imps = append(imps,
 			`"strconv"`,
@@ -197,9 +270,11 @@ func (m *HTTPServeMux) Impl(n *model.Node) model.PartImpl {
 	params := struct {
 		NodeName   string
 		Prometheus bool
+		Router     bool
 	}{
 		NodeName:   n.Name,
 		Prometheus: m.EnablePrometheus,
+		Router:     router,
 	}
 	if err := httpServeMuxBodyTmpl.Execute(bb, params); err != nil {
 		panic("executing httpservemux-body template: " + err.Error())
@@ -27,6 +27,7 @@ var (
 	httpServeMuxRoutesSession *dom.AceSession
 
 	inputHTTPServeMuxEnablePrometheus = doc.ElementByID("httpservemux-enableprometheus")
+	selectHTTPServeMuxMode            = doc.ElementByID("httpservemux-mode")
 
 	focusedHTTPServeMux *HTTPServeMux
 )
@@ -36,6 +37,9 @@ func init() {
 	inputHTTPServeMuxEnablePrometheus.AddEventListener("change", func(dom.Object) {
 		focusedHTTPServeMux.EnablePrometheus = inputHTTPServeMuxEnablePrometheus.Get("checked").Bool()
 	})
+	selectHTTPServeMuxMode.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServeMux.Mode = HTTPServeMuxMode(selectHTTPServeMuxMode.Get("value").String())
+	})
 }
 
 func httpServeMuxRoutesChange(dom.Object) {
@@ -56,4 +60,5 @@ func (m *HTTPServeMux) GainFocus() {
 	}
 	httpServeMuxRoutesSession.SetValue(string(routes))
 	inputHTTPServeMuxEnablePrometheus.Set("checked", focusedHTTPServeMux.EnablePrometheus)
+	selectHTTPServeMuxMode.Set("value", m.Mode)
 }
@@ -0,0 +1,238 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HTTPRouter routes requests by method, host, and path, capturing path
+// parameters. It is used by the HTTPServeMux part in router mode.
+//
+// Patterns have the form "[METHOD ][HOST]/PATH". PATH is split into
+// segments at each "/", and each segment is either a literal, a parameter
+// "{name}" matching any one non-empty segment, or (only as the last
+// segment) a parameter "{name...}" matching the remainder of the path.
+// Like http.ServeMux, a PATH ending in "/" matches every path with that
+// prefix. A method of GET also matches HEAD requests.
+//
+// When more than one pattern matches a request, the most specific one is
+// used: at the first segment where they differ, literals are more specific
+// than parameters, which are more specific than remainder parameters, and
+// longer paths are more specific than shorter ones. Between otherwise equal
+// paths, a pattern with a host is more specific than one without, and then
+// a pattern with a method is more specific than one without.
+type HTTPRouter struct {
+	routes []*httpRoute
+}
+
+type httpSegmentKind int
+
+const (
+	httpSegmentLiteral httpSegmentKind = iota
+	httpSegmentParam
+	httpSegmentRemainder
+	httpSegmentEnd
+)
+
+type httpSegment struct {
+	kind httpSegmentKind
+	text string // literal text or parameter name
+}
+
+type httpRoute struct {
+	method, host string
+	segs         []httpSegment
+	prefix       bool
+	handler      HTTPHandler
+}
+
+// NewHTTPRouter returns a new, empty HTTPRouter.
+func NewHTTPRouter() *HTTPRouter { return &HTTPRouter{} }
+
+// Handle adds a route. Like http.ServeMux.Handle, it panics if the pattern
+// is invalid.
+func (r *HTTPRouter) Handle(pattern string, h HTTPHandler) {
+	rt, err := parseHTTPRoute(pattern)
+	if err != nil {
+		panic(err)
+	}
+	rt.handler = h
+	r.routes = append(r.routes, rt)
+}
+
+func parseHTTPRoute(pattern string) (*httpRoute, error) {
+	rt := &httpRoute{}
+	rest := pattern
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		rt.method, rest = rest[:i], strings.TrimLeft(rest[i:], " \t")
+	}
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("pattern %q has no path", pattern)
+	}
+	rt.host, rest = strings.ToLower(rest[:i]), rest[i+1:]
+	segs := strings.Split(rest, "/")
+	if segs[len(segs)-1] == "" {
+		rt.prefix = true
+		segs = segs[:len(segs)-1]
+	}
+	seen := make(map[string]bool)
+	for i, s := range segs {
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			if strings.ContainsAny(s, "{}") {
+				return nil, fmt.Errorf("pattern %q has a bad segment %q", pattern, s)
+			}
+			rt.segs = append(rt.segs, httpSegment{kind: httpSegmentLiteral, text: s})
+			continue
+		}
+		seg := httpSegment{kind: httpSegmentParam, text: s[1 : len(s)-1]}
+		if strings.HasSuffix(seg.text, "...") {
+			if i != len(segs)-1 || rt.prefix {
+				return nil, fmt.Errorf("pattern %q has a remainder parameter that isn't last", pattern)
+			}
+			seg.kind, seg.text = httpSegmentRemainder, strings.TrimSuffix(seg.text, "...")
+		}
+		if seg.text == "" {
+			return nil, fmt.Errorf("pattern %q has an unnamed parameter", pattern)
+		}
+		if seen[seg.text] {
+			return nil, fmt.Errorf("pattern %q has duplicate parameter %q", pattern, seg.text)
+		}
+		seen[seg.text] = true
+		rt.segs = append(rt.segs, seg)
+	}
+	return rt, nil
+}
+
+// match reports whether the route matches the host and path segments,
+// ignoring the method, and if so returns any captured parameters.
+func (rt *httpRoute) match(host string, segs []string) (map[string]string, bool) {
+	if rt.host != "" && rt.host != host {
+		return nil, false
+	}
+	var params map[string]string
+	for i, s := range rt.segs {
+		if s.kind == httpSegmentRemainder {
+			if i >= len(segs) {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[s.text] = strings.Join(segs[i:], "/")
+			return params, true
+		}
+		if i >= len(segs) {
+			return nil, false
+		}
+		switch s.kind {
+		case httpSegmentLiteral:
+			if segs[i] != s.text {
+				return nil, false
+			}
+		case httpSegmentParam:
+			if segs[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[s.text] = segs[i]
+		}
+	}
+	if rt.prefix {
+		return params, len(segs) > len(rt.segs)
+	}
+	return params, len(segs) == len(rt.segs)
+}
+
+func (rt *httpRoute) matchMethod(method string) bool {
+	return rt.method == "" || rt.method == method || (rt.method == http.MethodGet && method == http.MethodHead)
+}
+
+// moreSpecific reports whether rt is more specific than other.
+func (rt *httpRoute) moreSpecific(other *httpRoute) bool {
+	for i := 0; ; i++ {
+		a, b := rt.kind(i), other.kind(i)
+		if a != b {
+			return a < b
+		}
+		if a == httpSegmentEnd || a == httpSegmentRemainder {
+			break
+		}
+	}
+	if (rt.host != "") != (other.host != "") {
+		return rt.host != ""
+	}
+	return rt.method != "" && other.method == ""
+}
+
+// kind returns the kind of the i-th segment. Past the end of a path, a
+// prefix pattern continues with a remainder.
+func (rt *httpRoute) kind(i int) httpSegmentKind {
+	switch {
+	case i < len(rt.segs):
+		return rt.segs[i].kind
+	case rt.prefix:
+		return httpSegmentRemainder
+	default:
+		return httpSegmentEnd
+	}
+}
+
+// Route finds the handler for a request, and any path parameters captured
+// by its pattern. If no pattern matches, the handler is nil, and allow
+// lists any methods that would have matched a pattern had the request used
+// one of those instead.
+func (r *HTTPRouter) Route(req *http.Request) (h HTTPHandler, params map[string]string, allow []string) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	segs := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+
+	var best *httpRoute
+	methods := make(map[string]bool)
+	for _, rt := range r.routes {
+		p, ok := rt.match(host, segs)
+		if !ok {
+			continue
+		}
+		if !rt.matchMethod(req.Method) {
+			methods[rt.method] = true
+			continue
+		}
+		if best == nil || rt.moreSpecific(best) {
+			best, params = rt, p
+		}
+	}
+	if best != nil {
+		return best.handler, params, nil
+	}
+	if methods[http.MethodGet] {
+		methods[http.MethodHead] = true
+	}
+	for m := range methods {
+		allow = append(allow, m)
+	}
+	sort.Strings(allow)
+	return nil, nil, allow
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPRouter(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/static/",
+		"/users/{id}",
+		"/users/new",
+		"GET /users/{id}/posts/{post}",
+		"/files/{path...}",
+		"example.com/",
+		"POST /things",
+		"PUT /things",
+		"GET /things",
+	}
+	r := NewHTTPRouter()
+	names := make(map[HTTPHandler]string)
+	for _, p := range patterns {
+		h := make(HTTPHandler)
+		names[h] = p
+		r.Handle(p, h)
+	}
+
+	tests := []struct {
+		method, target string
+		want           string
+		params         map[string]string
+		allow          []string
+	}{
+		{"GET", "http://x/", "/", nil, nil},
+		{"GET", "http://x/nope", "/", nil, nil},
+		{"GET", "http://x/static/a/b.css", "/static/", nil, nil},
+		{"GET", "http://x/static", "/", nil, nil},
+		{"GET", "http://x/users/42", "/users/{id}", map[string]string{"id": "42"}, nil},
+		{"GET", "http://x/users/new", "/users/new", nil, nil},
+		{"GET", "http://x/users/", "/", nil, nil},
+		{"GET", "http://x/users/42/posts/7", "GET /users/{id}/posts/{post}", map[string]string{"id": "42", "post": "7"}, nil},
+		{"HEAD", "http://x/users/42/posts/7", "GET /users/{id}/posts/{post}", map[string]string{"id": "42", "post": "7"}, nil},
+		{"POST", "http://x/users/42/posts/7", "/", nil, nil},
+		{"GET", "http://x/files/a/b/c", "/files/{path...}", map[string]string{"path": "a/b/c"}, nil},
+		{"GET", "http://x/files", "/", nil, nil},
+		{"GET", "http://EXAMPLE.com:8080/users/42", "/users/{id}", map[string]string{"id": "42"}, nil},
+		{"GET", "http://example.com/nope", "example.com/", nil, nil},
+		{"PUT", "http://x/things", "PUT /things", nil, nil},
+		{"DELETE", "http://x/things", "/", nil, nil},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.target, nil)
+		h, params, allow := r.Route(req)
+		if got := names[h]; got != test.want {
+			t.Errorf("Route(%s %s) handler = %q, want %q", test.method, test.target, got, test.want)
+		}
+		if !reflect.DeepEqual(params, test.params) {
+			t.Errorf("Route(%s %s) params = %v, want %v", test.method, test.target, params, test.params)
+		}
+		if !reflect.DeepEqual(allow, test.allow) {
+			t.Errorf("Route(%s %s) allow = %v, want %v", test.method, test.target, allow, test.allow)
+		}
+	}
+}
+
+func TestHTTPRouterMethodNotAllowed(t *testing.T) {
+	r := NewHTTPRouter()
+	r.Handle("POST /things", make(HTTPHandler))
+	r.Handle("GET /things", make(HTTPHandler))
+	r.Handle("PUT /things/{id}", make(HTTPHandler))
+
+	req := httptest.NewRequest("DELETE", "/things", nil)
+	h, params, allow := r.Route(req)
+	if h != nil || params != nil {
+		t.Errorf("Route(DELETE /things) = %v, %v, want nil handler and params", h, params)
+	}
+	if want := []string{"GET", "HEAD", "POST"}; !reflect.DeepEqual(allow, want) {
+		t.Errorf("Route(DELETE /things) allow = %v, want %v", allow, want)
+	}
+
+	req = httptest.NewRequest("GET", "/other", nil)
+	if h, _, allow := r.Route(req); h != nil || allow != nil {
+		t.Errorf("Route(GET /other) = %v, _, %v, want nil handler and allow", h, allow)
+	}
+}
+
+func TestHTTPRouterBadPatterns(t *testing.T) {
+	for _, p := range []string{
+		"nopath",
+		"GET nopath",
+		"/a/{}",
+		"/a/{x...}/b",
+		"/a/{x...}/",
+		"/a/{x}/{x}",
+		"/a/b{x}",
+	} {
+		if _, err := parseHTTPRoute(p); err == nil {
+			t.Errorf("parseHTTPRoute(%q) error = nil, want error", p)
+		}
+	}
+}
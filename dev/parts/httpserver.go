@@ -70,6 +70,46 @@ func init() {
 				</div>
 			</div>`,
 			},
+			{
+				Name: "Listener",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="httpserver-network">Network</label>
+					<select id="httpserver-network" name="httpserver-network">
+						<option value="tcp" selected>TCP</option>
+						<option value="unix">Unix domain socket</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="httpserver-tls">TLS</label>
+					<select id="httpserver-tls" name="httpserver-tls">
+						<option value="none" selected>None</option>
+						<option value="files">Certificate and key files</option>
+						<option value="selfsigned">Self-signed (development only)</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="httpserver-certfile">Certificate file</label>
+					<input id="httpserver-certfile" name="httpserver-certfile" type="text"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpserver-keyfile">Key file</label>
+					<input id="httpserver-keyfile" name="httpserver-keyfile" type="text"></input>
+				</div>
+				<div class="formfield">
+					<input id="httpserver-disablehttp2" name="httpserver-disablehttp2" type="checkbox"></input>
+					<label for="httpserver-disablehttp2">Disable HTTP/2</label>
+				</div>
+				<div class="formfield">
+					<label for="httpserver-http2maxconcurrentstreams">HTTP/2 max concurrent streams</label>
+					<input id="httpserver-http2maxconcurrentstreams" name="httpserver-http2maxconcurrentstreams" type="number" required min="0" title="Must be a whole number. 0 means the default." value="0"></input>
+				</div>
+				<div class="formfield">
+					<label for="httpserver-http2maxreadframesize">HTTP/2 max read frame size</label>
+					<input id="httpserver-http2maxreadframesize" name="httpserver-http2maxreadframesize" type="number" required min="0" title="Must be a whole number. 0 means the default." value="0"></input>
+				</div>
+			</div>`,
+			},
 			{
 				Name: "Help",
 				Editor: `<div>
@@ -80,6 +120,19 @@ func init() {
 			    listens on the address given by the manager.
 				It continues running until the manager's Wait returns (after Shutdown), 
 				at which point it will read the next manager.
+			</p><p>
+				If the manager also implements <code>parts.HTTPServerListener</code>
+				(such as those made with <code>parts.NewHTTPServerListenerManager</code>),
+				the server uses the listener it supplies. Otherwise the server listens
+				on the network: for TCP the address is a host and port, and for a Unix
+				domain socket it is the path of the socket file. A socket file left over
+				from a previous run is removed first.
+			</p><p>
+				With TLS, the server uses the certificate and key files given (in PEM
+				format), or a certificate generated when the server starts, which is
+				self-signed so that clients won't trust it unless they are told to.
+				HTTP/2 is only available with TLS, and is enabled unless disabled. The
+				HTTP/2 settings are zero for the defaults.
 			</p><p>
 				The outputs are shared by all servers started by this part. If different
 				request handling or error paths are needed for different servers, then use 
@@ -97,6 +150,16 @@ func init() {
 	})
 }
 
+// HTTPServerTLSMode is a choice of TLS configuration for a HTTPServer part.
+type HTTPServerTLSMode string
+
+// Values for HTTPServerTLSMode.
+const (
+	HTTPServerTLSNone       HTTPServerTLSMode = "none"
+	HTTPServerTLSFiles      HTTPServerTLSMode = "files"
+	HTTPServerTLSSelfSigned HTTPServerTLSMode = "selfsigned"
+)
+
 // HTTPServer is a part which listens on an address and
 // serves HTTP requests.
 type HTTPServer struct {
@@ -105,6 +168,15 @@ type HTTPServer struct {
 	WriteTimeout      time.Duration `json:"write_timeout,omitempty"`
 	IdleTimeout       time.Duration `json:"idle_timeout,omitempty"`
 	MaxHeaderBytes    int           `json:"max_header_bytes,omitempty"`
+
+	Network  string            `json:"network,omitempty"`
+	TLS      HTTPServerTLSMode `json:"tls,omitempty"`
+	CertFile string            `json:"cert_file,omitempty"`
+	KeyFile  string            `json:"key_file,omitempty"`
+
+	DisableHTTP2              bool   `json:"disable_http2,omitempty"`
+	HTTP2MaxConcurrentStreams uint32 `json:"http2_max_concurrent_streams,omitempty"`
+	HTTP2MaxReadFrameSize     uint32 `json:"http2_max_read_frame_size,omitempty"`
 }
 
 // Clone returns a clone of this HTTPServer.
@@ -112,6 +184,34 @@ func (s *HTTPServer) Clone() model.Part { s0 := *s; return &s0 }
 
 // Impl returns the HTTPServer implementation.
 func (s *HTTPServer) Impl(*model.Node) model.PartImpl {
+	imps := []string{
+		`"net/http"`,
+		`"github.com/google/shenzhen-go/dev/parts"`,
+	}
+	switch s.Network {
+	case "", "tcp", "unix":
+		// Handled by parts.HTTPServerListen.
+	default:
+		panic("unknown network " + s.Network)
+	}
+	useTLS := true
+	switch s.TLS {
+	case "", HTTPServerTLSNone:
+		useTLS = false
+	case HTTPServerTLSFiles:
+	case HTTPServerTLSSelfSigned:
+		imps = append(imps, `"crypto/tls"`)
+	default:
+		panic("unknown TLS mode " + s.TLS)
+	}
+	configureHTTP2 := useTLS && !s.DisableHTTP2 && (s.HTTP2MaxConcurrentStreams != 0 || s.HTTP2MaxReadFrameSize != 0)
+	if configureHTTP2 {
+		imps = append(imps, `"golang.org/x/net/http2"`)
+	}
+	if useTLS && s.DisableHTTP2 {
+		imps = append(imps, `"crypto/tls"`)
+	}
+
 	b := bytes.NewBuffer(nil)
 	b.WriteString(`
 	for mgr := range manager {
@@ -134,10 +234,47 @@ func (s *HTTPServer) Impl(*model.Node) model.PartImpl {
 	if s.MaxHeaderBytes != 0 {
 		fmt.Fprintf(b, "MaxHeaderBytes: %d,\n", s.MaxHeaderBytes)
 	}
+	if useTLS && s.DisableHTTP2 {
+		// A non-nil, empty TLSNextProto turns off the automatic HTTP/2 support.
+		b.WriteString("TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),\n")
+	}
+	b.WriteString(`}
+		serve := func() error {
+		`)
+	if configureHTTP2 {
+		fmt.Fprintf(b, `if err := http2.ConfigureServer(svr, &http2.Server{
+				MaxConcurrentStreams: %d,
+				MaxReadFrameSize: %d,
+			}); err != nil {
+				return err
+			}
+			`, s.HTTP2MaxConcurrentStreams, s.HTTP2MaxReadFrameSize)
+	}
+	if s.TLS == HTTPServerTLSSelfSigned {
+		b.WriteString(`cert, err := parts.SelfSignedCertificate(mgr.Addr())
+			if err != nil {
+				return err
+			}
+			svr.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+			`)
+	}
+	fmt.Fprintf(b, `l, err := parts.HTTPServerListen(mgr, %q)
+			if err != nil {
+				return err
+			}
+			`, s.Network)
+	switch {
+	case s.TLS == HTTPServerTLSFiles:
+		fmt.Fprintf(b, "return svr.ServeTLS(l, %q, %q)\n", s.CertFile, s.KeyFile)
+	case useTLS:
+		b.WriteString("return svr.ServeTLS(l, \"\", \"\")\n")
+	default:
+		b.WriteString("return svr.Serve(l)\n")
+	}
 	b.WriteString(`}
 		done := make(chan struct{})
 		go func() {
-			if err := svr.ListenAndServe(); err != nil && errors != nil {
+			if err := serve(); err != nil && errors != nil {
 				errors <- err
 			}
 			close(done)
@@ -148,11 +285,8 @@ func (s *HTTPServer) Impl(*model.Node) model.PartImpl {
 		<-done
 	}`)
 	return model.PartImpl{
-		Imports: []string{
-			`"net/http"`,
-			`"github.com/google/shenzhen-go/dev/parts"`,
-		},
-		Body: b.String(),
+		Imports: imps,
+		Body:    b.String(),
 		Tail: `close(requests)
 		if errors != nil {
 			close(errors)
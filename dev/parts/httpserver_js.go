@@ -30,12 +30,28 @@ var (
 		inputWriteTimeout      dom.Element
 		inputIdleTimeout       dom.Element
 		inputMaxHeaderBytes    dom.Element
+
+		selectNetwork                  dom.Element
+		selectTLS                      dom.Element
+		inputCertFile                  dom.Element
+		inputKeyFile                   dom.Element
+		inputDisableHTTP2              dom.Element
+		inputHTTP2MaxConcurrentStreams dom.Element
+		inputHTTP2MaxReadFrameSize     dom.Element
 	}{
 		inputReadTimeout:       doc.ElementByID("httpserver-readtimeout"),
 		inputReadHeaderTimeout: doc.ElementByID("httpserver-readheadertimeout"),
 		inputWriteTimeout:      doc.ElementByID("httpserver-writetimeout"),
 		inputIdleTimeout:       doc.ElementByID("httpserver-idletimeout"),
 		inputMaxHeaderBytes:    doc.ElementByID("httpserver-maxheaderbytes"),
+
+		selectNetwork:                  doc.ElementByID("httpserver-network"),
+		selectTLS:                      doc.ElementByID("httpserver-tls"),
+		inputCertFile:                  doc.ElementByID("httpserver-certfile"),
+		inputKeyFile:                   doc.ElementByID("httpserver-keyfile"),
+		inputDisableHTTP2:              doc.ElementByID("httpserver-disablehttp2"),
+		inputHTTP2MaxConcurrentStreams: doc.ElementByID("httpserver-http2maxconcurrentstreams"),
+		inputHTTP2MaxReadFrameSize:     doc.ElementByID("httpserver-http2maxreadframesize"),
 	}
 
 	focusedHTTPServer *HTTPServer
@@ -61,6 +77,29 @@ func init() {
 	httpServerOutlets.inputMaxHeaderBytes.AddEventListener("change", func(dom.Object) {
 		focusedHTTPServer.MaxHeaderBytes = httpServerOutlets.inputMaxHeaderBytes.Get("value").Int()
 	})
+
+	o := &httpServerOutlets
+	o.selectNetwork.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.Network = o.selectNetwork.Get("value").String()
+	})
+	o.selectTLS.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.TLS = HTTPServerTLSMode(o.selectTLS.Get("value").String())
+	})
+	o.inputCertFile.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.CertFile = o.inputCertFile.Get("value").String()
+	})
+	o.inputKeyFile.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.KeyFile = o.inputKeyFile.Get("value").String()
+	})
+	o.inputDisableHTTP2.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.DisableHTTP2 = o.inputDisableHTTP2.Get("checked").Bool()
+	})
+	o.inputHTTP2MaxConcurrentStreams.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.HTTP2MaxConcurrentStreams = uint32(o.inputHTTP2MaxConcurrentStreams.Get("value").Int())
+	})
+	o.inputHTTP2MaxReadFrameSize.AddEventListener("change", func(dom.Object) {
+		focusedHTTPServer.HTTP2MaxReadFrameSize = uint32(o.inputHTTP2MaxReadFrameSize.Get("value").Int())
+	})
 }
 
 func setReadTimeout(t time.Duration)       { focusedHTTPServer.ReadTimeout = t }
@@ -75,4 +114,11 @@ func (s *HTTPServer) GainFocus() {
 	httpServerOutlets.inputWriteTimeout.Set("value", focusedHTTPServer.WriteTimeout.String())
 	httpServerOutlets.inputIdleTimeout.Set("value", focusedHTTPServer.IdleTimeout.String())
 	httpServerOutlets.inputMaxHeaderBytes.Set("value", focusedHTTPServer.MaxHeaderBytes)
+	httpServerOutlets.selectNetwork.Set("value", focusedHTTPServer.Network)
+	httpServerOutlets.selectTLS.Set("value", focusedHTTPServer.TLS)
+	httpServerOutlets.inputCertFile.Set("value", focusedHTTPServer.CertFile)
+	httpServerOutlets.inputKeyFile.Set("value", focusedHTTPServer.KeyFile)
+	httpServerOutlets.inputDisableHTTP2.Set("checked", focusedHTTPServer.DisableHTTP2)
+	httpServerOutlets.inputHTTP2MaxConcurrentStreams.Set("value", focusedHTTPServer.HTTP2MaxConcurrentStreams)
+	httpServerOutlets.inputHTTP2MaxReadFrameSize.Set("value", focusedHTTPServer.HTTP2MaxReadFrameSize)
 }
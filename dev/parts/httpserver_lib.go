@@ -16,16 +16,31 @@ package parts
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"log"
+	"math/big"
+	"net"
 	"net/http"
+	"os"
 	"runtime"
+	"time"
 )
 
 // HTTPRequest represents incoming HTTP requests and the means to respond to them.
 type HTTPRequest struct {
 	http.ResponseWriter
 	Request *http.Request
-	done    chan struct{}
+
+	// Params holds any path parameters captured by a HTTPServeMux
+	// in router mode.
+	Params map[string]string
+
+	done chan struct{}
 }
 
 // Close completes the request.
@@ -36,10 +51,23 @@ func (r *HTTPRequest) Close() error { close(r.done); r.done = nil; return nil }
 type HTTPHandler chan<- *HTTPRequest
 
 func (h HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, nil)
+}
+
+// Forward sends a new request to h that responds with w, but is otherwise
+// the same as r (including Params), and waits until it is closed.
+// This is useful for parts that need to wrap the ResponseWriter. Note that
+// r itself still needs closing afterwards.
+func (r *HTTPRequest) Forward(h HTTPHandler, w http.ResponseWriter) {
+	h.serve(w, r.Request, r.Params)
+}
+
+func (h HTTPHandler) serve(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	done := make(chan struct{})
 	hr := &HTTPRequest{
 		ResponseWriter: w,
 		Request:        r,
+		Params:         params,
 		done:           done,
 	}
 	// Crawshaw-style sharp-edged finalizers are nice but it's possible some
@@ -61,7 +89,9 @@ func (h HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //
 // You can implement your own if you really want, but NewHTTPServerManager
 // returns a simple, straightforward, channel-based implementation.
-// The HTTPServer part only requires Addr and Wait.
+// The HTTPServer part only requires Addr and Wait. To serve on a listener
+// other than one the HTTPServer part creates itself, implement
+// HTTPServerListener too.
 type HTTPServerManager interface {
 	// Addr is the listen address for the HTTP server.
 	Addr() string
@@ -91,3 +121,111 @@ func NewHTTPServerManager(addr string) HTTPServerManager {
 func (h *httpServerManager) Addr() string                 { return h.addr }
 func (h *httpServerManager) Shutdown(ctx context.Context) { h.shutdown <- ctx }
 func (h *httpServerManager) Wait() context.Context        { return <-h.shutdown }
+
+// HTTPServerListener is an optional interface for HTTPServerManagers that
+// supply their own listener (for example, one inherited from a parent process
+// or created by a test). If a manager implements it, the HTTPServer part
+// serves on the listener from Listener instead of listening on Addr.
+type HTTPServerListener interface {
+	HTTPServerManager
+
+	// Listener returns the listener to serve on. The HTTP server closes
+	// it when shut down.
+	Listener() (net.Listener, error)
+}
+
+type httpServerListenerManager struct {
+	httpServerManager
+	listener net.Listener
+}
+
+// NewHTTPServerListenerManager creates a channel-based HTTPServerManager that
+// also implements HTTPServerListener, serving on l.
+func NewHTTPServerListenerManager(l net.Listener) HTTPServerManager {
+	return &httpServerListenerManager{
+		httpServerManager: httpServerManager{
+			addr:     l.Addr().String(),
+			shutdown: make(chan context.Context),
+		},
+		listener: l,
+	}
+}
+
+func (h *httpServerListenerManager) Listener() (net.Listener, error) { return h.listener, nil }
+
+// HTTPServerListen returns a listener for a HTTPServer part to serve on.
+// This is the manager's own listener if it implements HTTPServerListener,
+// or else a new listener on the network ("tcp" or "unix") and the manager's
+// Addr. Before listening on a Unix socket, any socket file left over at the
+// same path is removed.
+func HTTPServerListen(mgr HTTPServerManager, network string) (net.Listener, error) {
+	return managerListen(mgr, network, ":http")
+}
+
+// managerListen returns the listener from mgr, if it has one, or else listens
+// on the network and mgr's address. defaultAddr is used for an empty TCP address.
+func managerListen(mgr interface{ Addr() string }, network, defaultAddr string) (net.Listener, error) {
+	if hl, ok := mgr.(interface {
+		Listener() (net.Listener, error)
+	}); ok {
+		return hl.Listener()
+	}
+	addr := mgr.Addr()
+	switch network {
+	case "", "tcp":
+		if addr == "" {
+			addr = defaultAddr
+		}
+		return net.Listen("tcp", addr)
+	case "unix":
+		if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(addr); err != nil {
+				return nil, err
+			}
+		}
+		return net.Listen("unix", addr)
+	default:
+		return nil, &net.OpError{Op: "listen", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+}
+
+// SelfSignedCertificate generates a self-signed certificate, valid for a year,
+// for localhost and the host part of addr (if any). It is meant for
+// development only: clients won't trust it without being told to.
+func SelfSignedCertificate(addr string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Shenzhen Go development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if host != "localhost" {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
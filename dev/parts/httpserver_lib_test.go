@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTTPServerListenListenerManager(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	defer l.Close()
+	mgr := NewHTTPServerListenerManager(l)
+	if got, want := mgr.Addr(), l.Addr().String(); got != want {
+		t.Errorf("mgr.Addr() = %q, want %q", got, want)
+	}
+	got, err := HTTPServerListen(mgr, "unix")
+	if err != nil {
+		t.Fatalf("HTTPServerListen() = %v", err)
+	}
+	if got != l {
+		t.Errorf("HTTPServerListen() = %v, want the manager's listener %v", got, l)
+	}
+}
+
+func TestHTTPServerListenUnixStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpserver")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sock")
+
+	// Leave a stale socket file behind.
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	l, err = HTTPServerListen(NewHTTPServerManager(path), "unix")
+	if err != nil {
+		t.Fatalf("HTTPServerListen() = %v", err)
+	}
+	l.Close()
+
+	// But don't remove other files.
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile() = %v", err)
+	}
+	if l, err := HTTPServerListen(NewHTTPServerManager(path), "unix"); err == nil {
+		l.Close()
+		t.Error("HTTPServerListen() error = nil, want error for existing regular file")
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := SelfSignedCertificate("example.com:8443")
+	if err != nil {
+		t.Fatalf("SelfSignedCertificate() = %v", err)
+	}
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() = %v", err)
+	}
+	for _, host := range []string{"localhost", "example.com", "127.0.0.1", "::1"} {
+		if err := x.VerifyHostname(host); err != nil {
+			t.Errorf("VerifyHostname(%q) = %v", host, err)
+		}
+	}
+	if err := x.VerifyHostname("example.org"); err == nil {
+		t.Error("VerifyHostname(example.org) = nil, want error")
+	}
+}
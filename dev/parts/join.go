@@ -0,0 +1,345 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const (
+	joinKeyTypeParam   = "$K"
+	joinLeftTypeParam  = "$L"
+	joinRightTypeParam = "$R"
+)
+
+func joinOutputType(kt, lt, rt string) string {
+	return fmt.Sprintf("struct{ Key %s; Left %s; Right %s }", kt, lt, rt)
+}
+
+var (
+	joinPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "left",
+			Direction: pin.Input,
+			Type:      joinLeftTypeParam,
+		},
+		&pin.Definition{
+			Name:      "right",
+			Direction: pin.Input,
+			Type:      joinRightTypeParam,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      joinOutputType(joinKeyTypeParam, joinLeftTypeParam, joinRightTypeParam),
+		},
+		&pin.Definition{
+			Name:      "unmatched_left",
+			Direction: pin.Output,
+			Type:      joinLeftTypeParam,
+		},
+		&pin.Definition{
+			Name:      "unmatched_right",
+			Direction: pin.Output,
+			Type:      joinRightTypeParam,
+		},
+	)
+
+	// Each side keeps pending (unmatched) entries in a per-key FIFO, and
+	// in an arrival-order FIFO for expiry. Matched entries are marked done
+	// and removed from the per-key FIFO immediately, but are only removed
+	// from the arrival-order FIFO when they reach the front.
+	joinBodyTmpl = template.Must(template.New("join-body").Parse(`
+	{{if .TimeWindow -}}
+	const window = {{.WindowNanos}} // {{.Window}}
+	{{- else -}}
+	const window = {{.WindowCount}}
+	{{- end}}
+	{{range .Sides -}}
+	type {{.Name}}Entry struct {
+		key   {{$.KeyType}}
+		value {{.Type}}
+		{{if $.TimeWindow}}at    time.Time{{end}}
+		done  bool
+	}
+	{{.Name}}ByKey := make(map[{{$.KeyType}}][]*{{.Name}}Entry)
+	var {{.Name}}Order []*{{.Name}}Entry
+
+	// expire{{.Title}} removes the oldest {{.Name}} entry and sends it to unmatched_{{.Name}}
+	// if it wasn't matched.
+	expire{{.Title}} := func() {
+		e := {{.Name}}Order[0]
+		{{.Name}}Order = {{.Name}}Order[1:]
+		if e.done {
+			return
+		}
+		// Pending entries are in arrival order, so e is first for its key.
+		if q := {{.Name}}ByKey[e.key][1:]; len(q) > 0 {
+			{{.Name}}ByKey[e.key] = q
+		} else {
+			delete({{.Name}}ByKey, e.key)
+		}
+		if unmatched_{{.Name}} != nil {
+			unmatched_{{.Name}} <- e.value
+		}
+	}
+	{{end}}
+	{{if .TimeWindow -}}
+	expireAll := func(now time.Time) {
+		{{range .Sides -}}
+		for len({{.Name}}Order) > 0 && ({{.Name}}Order[0].done || now.Sub({{.Name}}Order[0].at) > window) {
+			expire{{.Title}}()
+		}
+		{{end -}}
+	}
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	{{end}}
+	for left != nil || right != nil {
+		select {
+		{{range .Sides -}}
+		case in, open := <-{{.Name}}:
+			if !open {
+				{{.Name}} = nil
+				continue
+			}
+			key := {{$.KeyType}}({{.Key}})
+			{{if $.TimeWindow -}}
+			now := time.Now()
+			expireAll(now)
+			{{end -}}
+			if q := {{.Other}}ByKey[key]; len(q) > 0 {
+				m := q[0]
+				m.done = true
+				if len(q) > 1 {
+					{{.Other}}ByKey[key] = q[1:]
+				} else {
+					delete({{.Other}}ByKey, key)
+				}
+				if output != nil {
+					output <- {{$.OutputType}}{
+						Key:   key,
+						{{.Title}}:  in,
+						{{.OtherTitle}}: m.value,
+					}
+				}
+				continue
+			}
+			e := &{{.Name}}Entry{
+				key:   key,
+				value: in,
+				{{if $.TimeWindow}}at: now,{{end}}
+			}
+			{{.Name}}ByKey[key] = append({{.Name}}ByKey[key], e)
+			{{.Name}}Order = append({{.Name}}Order, e)
+			{{if not $.TimeWindow -}}
+			if len({{.Name}}Order) > window {
+				expire{{.Title}}()
+			}
+			{{end -}}
+		{{end -}}
+		{{if .TimeWindow -}}
+		case now := <-ticker.C:
+			expireAll(now)
+		{{end -}}
+		}
+	}
+
+	// Everything still pending is unmatched.
+	{{range .Sides -}}
+	for len({{.Name}}Order) > 0 {
+		expire{{.Title}}()
+	}
+	{{end -}}`))
+)
+
+func init() {
+	model.RegisterPartType("Join", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Join{
+				LeftKey:    "in",
+				RightKey:   "in",
+				WindowMode: JoinWindowTime,
+				Window:     time.Second,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Join",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="join-leftkey">Left key expression</label>
+					<input id="join-leftkey" name="join-leftkey" type="text" required title="Must be a Go expression using in" value="in"></input>
+				</div>
+				<div class="formfield">
+					<label for="join-rightkey">Right key expression</label>
+					<input id="join-rightkey" name="join-rightkey" type="text" required title="Must be a Go expression using in" value="in"></input>
+				</div>
+				<div class="formfield">
+					<label for="join-windowmode">Window mode</label>
+					<select id="join-windowmode" name="join-windowmode">
+						<option value="time" selected>Time</option>
+						<option value="count">Count</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="join-window">Time window</label>
+					<input id="join-window" name="join-window" type="text" required title="Must be a parseable time.Duration. 0s means the default, 1s." value="1s"></input>
+				</div>
+				<div class="formfield">
+					<label for="join-windowcount">Count window</label>
+					<input id="join-windowcount" name="join-windowcount" type="number" required title="Must be a whole number, at least 1." value="100"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Join part correlates values from two inputs, left and right, by key.
+				When a value arrives that has the same key as an unmatched value from
+				the other input, the pair is sent on the output as a struct with
+				fields Key, Left, and Right. Each value is matched at most once, and
+				the oldest unmatched value with the key is matched first.
+			</p><p>
+				The key expressions are Go expressions in terms of the input value
+				<code>in</code>, for example <code>in.RequestID</code>. Both key
+				expressions must produce values of the same comparable type.
+			</p><p>
+				Unmatched values are kept for a limited window. In Time mode, values
+				are kept for the time window after they arrive (one second, if the
+				window is 0s). In Count mode, only
+				the most recent values (up to the count window) from each input are
+				kept. Values that leave the window without being matched are sent to
+				unmatched_left or unmatched_right.
+			</p><p>
+				When both inputs are closed, all remaining unmatched values are sent
+				to unmatched_left or unmatched_right, and the outputs are closed.
+			</p><p>
+				Each instance joins independently, so multiplicity should usually be 1.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// JoinWindowMode describes how long a Join keeps unmatched values.
+type JoinWindowMode string
+
+// Valid values of JoinWindowMode.
+const (
+	JoinWindowTime  JoinWindowMode = "time"
+	JoinWindowCount JoinWindowMode = "count"
+)
+
+// Join is a part which joins two streams by key.
+type Join struct {
+	LeftKey     string         `json:"left_key"`
+	RightKey    string         `json:"right_key"`
+	WindowMode  JoinWindowMode `json:"window_mode"`
+	Window      time.Duration  `json:"window,omitempty"`
+	WindowCount uint           `json:"window_count,omitempty"`
+}
+
+// Clone returns a clone of this Join.
+func (j *Join) Clone() model.Part {
+	j0 := *j
+	return &j0
+}
+
+type joinSide struct {
+	Name, Title, Other, OtherTitle, Type, Key string
+}
+
+// Impl returns the Join implementation.
+func (j *Join) Impl(n *model.Node) model.PartImpl {
+	kt := n.TypeParams[joinKeyTypeParam].String()
+	lt := n.TypeParams[joinLeftTypeParam].String()
+	rt := n.TypeParams[joinRightTypeParam].String()
+	lk, rk := j.LeftKey, j.RightKey
+	if lk == "" {
+		lk = "in"
+	}
+	if rk == "" {
+		rk = "in"
+	}
+	params := struct {
+		KeyType, OutputType string
+		TimeWindow          bool
+		Window              time.Duration
+		WindowNanos         int64
+		WindowCount         uint
+		Sides               []joinSide
+	}{
+		KeyType:     kt,
+		OutputType:  joinOutputType(kt, lt, rt),
+		Window:      j.Window,
+		WindowNanos: int64(j.Window),
+		WindowCount: j.WindowCount,
+		Sides: []joinSide{
+			{Name: "left", Title: "Left", Other: "right", OtherTitle: "Right", Type: lt, Key: lk},
+			{Name: "right", Title: "Right", Other: "left", OtherTitle: "Left", Type: rt, Key: rk},
+		},
+	}
+	switch j.WindowMode {
+	case JoinWindowTime:
+		params.TimeWindow = true
+		if params.Window <= 0 {
+			params.Window = time.Second
+		}
+		params.WindowNanos = int64(params.Window)
+	case JoinWindowCount:
+		if params.WindowCount < 1 {
+			params.WindowCount = 1
+		}
+	default:
+		panic("unknown window mode " + j.WindowMode)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := joinBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute join-body template: " + err.Error())
+	}
+	var imps []string
+	if params.TimeWindow {
+		imps = append(imps, `"time"`)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    b.String(),
+		Tail: `if output != nil {
+			close(output)
+		}
+		if unmatched_left != nil {
+			close(unmatched_left)
+		}
+		if unmatched_right != nil {
+			close(unmatched_right)
+		}`,
+	}
+}
+
+// Pins returns a map declaring the left and right inputs, the output,
+// and the unmatched outputs.
+func (j *Join) Pins() pin.Map { return joinPins }
+
+// TypeKey returns "Join".
+func (j *Join) TypeKey() string { return "Join" }
@@ -0,0 +1,60 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	inputJoinLeftKey     = doc.ElementByID("join-leftkey")
+	inputJoinRightKey    = doc.ElementByID("join-rightkey")
+	selectJoinWindowMode = doc.ElementByID("join-windowmode")
+	inputJoinWindow      = doc.ElementByID("join-window")
+	inputJoinWindowCount = doc.ElementByID("join-windowcount")
+
+	focusedJoin *Join
+)
+
+func init() {
+	inputJoinLeftKey.AddEventListener("change", func(dom.Object) {
+		focusedJoin.LeftKey = inputJoinLeftKey.Get("value").String()
+	})
+	inputJoinRightKey.AddEventListener("change", func(dom.Object) {
+		focusedJoin.RightKey = inputJoinRightKey.Get("value").String()
+	})
+	selectJoinWindowMode.AddEventListener("change", func(dom.Object) {
+		focusedJoin.WindowMode = JoinWindowMode(selectJoinWindowMode.Get("value").String())
+	})
+	inputJoinWindow.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedJoin.Window = t
+	}))
+	inputJoinWindowCount.AddEventListener("change", func(dom.Object) {
+		focusedJoin.WindowCount = uint(inputJoinWindowCount.Get("value").Int())
+	})
+}
+
+func (j *Join) GainFocus() {
+	focusedJoin = j
+	inputJoinLeftKey.Set("value", j.LeftKey)
+	inputJoinRightKey.Set("value", j.RightKey)
+	selectJoinWindowMode.Set("value", j.WindowMode)
+	inputJoinWindow.Set("value", j.Window.String())
+	inputJoinWindowCount.Set("value", j.WindowCount)
+}
@@ -15,7 +15,27 @@
 // Package parts contains various pre-made bits and pieces to combine into the graph.
 package parts
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/source"
+)
+
+// resolveType substitutes the node's inferred type parameters into a
+// generic type string. It requires the types to have been inferred
+// (as they are during Impl).
+func resolveType(n *model.Node, t string) string {
+	typ := source.MustNewType(n.Name, t)
+	types := make(source.TypeInferenceMap, len(n.TypeParams))
+	for ident, pt := range n.TypeParams {
+		types[source.TypeParam{Scope: n.Name, Ident: ident}] = pt
+	}
+	if _, err := typ.Refine(types); err != nil {
+		panic("couldn't refine type " + t + ": " + err.Error())
+	}
+	return typ.String()
+}
 
 func stripCR(in []string) []string {
 	for i := range in {
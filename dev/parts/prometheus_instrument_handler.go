@@ -142,6 +142,7 @@ func (h *PrometheusInstrumentHandler) Clone() model.Part {
 func (h *PrometheusInstrumentHandler) Impl(n *model.Node) model.PartImpl {
 	return model.PartImpl{
 		Imports: []string{
+			`"net/http"`,
 			`"github.com/google/shenzhen-go/dev/parts"`,
 			`"github.com/prometheus/client_golang/prometheus"`,
 			`"github.com/prometheus/client_golang/prometheus/promhttp"`,
@@ -159,9 +160,13 @@ func (h *PrometheusInstrumentHandler) Impl(n *model.Node) model.PartImpl {
 					%#v)
 		prometheus.MustRegister(sum)
 		`, model.Mangle(n.Name), h.Instrumenter.help(), h.Buckets, h.labels()),
+		// Requests are forwarded with Forward, to keep any Params.
 		Body: fmt.Sprintf(`
-		h := promhttp.InstrumentHandler%s(sum, parts.HTTPHandler(out))
+		next := parts.HTTPHandler(out)
 		for r := range in {
+			h := promhttp.InstrumentHandler%s(sum, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				r.Forward(next, w)
+			}))
 			h.ServeHTTP(r.ResponseWriter, r.Request)
 			r.Close()
 		}`, h.Instrumenter),
@@ -0,0 +1,266 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const pubSubTypeParam = "$T"
+
+func pubSubSubscribeType(t string) string {
+	return fmt.Sprintf("struct{ Pattern string; Ch chan<- %s; Done <-chan struct{} }", t)
+}
+
+var (
+	pubSubPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "publish",
+			Direction: pin.Input,
+			Type:      pubSubTypeParam,
+		},
+		&pin.Definition{
+			Name:      "subscribe",
+			Direction: pin.Input,
+			Type:      pubSubSubscribeType(pubSubTypeParam),
+		},
+	)
+
+	// The subscribers are shared by all instances. Each subscriber has a
+	// goroutine waiting for it to be done, which then removes it. The last
+	// instance to finish publishing closes the remaining subscribers, once
+	// every other instance has delivered its last message.
+	//
+	// Messages are sent without holding mu, so a slow subscriber doesn't hold
+	// up subscribing, unsubscribing, or the other instances. Each subscriber
+	// has its own lock, so its channel is never closed during a send.
+	pubSubHeadTmpl = template.Must(template.New("pubsub-head").Parse(`
+	type subscriber struct {
+		pattern string
+		done    <-chan struct{}
+
+		mu     sync.Mutex // guards ch and closed
+		ch     chan<- {{.Type}}
+		closed bool
+	}
+	var mu sync.Mutex
+	subs := make(map[*subscriber]struct{})
+	publishing := multiplicity
+	quit := make(chan struct{})
+	// remove must be called with mu held.
+	remove := func(s *subscriber) {
+		if _, ok := subs[s]; !ok {
+			return
+		}
+		delete(subs, s)
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	}
+	publishDone := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if publishing--; publishing > 0 {
+			return
+		}
+		close(quit)
+		for s := range subs {
+			remove(s)
+		}
+	}`))
+
+	pubSubBodyTmpl = template.Must(template.New("pubsub-body").Parse(`
+	{{if .Mult -}}
+	// Channels are set to nil when closed, so each instance needs its own.
+	publish, subscribe := publish, subscribe
+	{{end -}}
+	if publish == nil {
+		publishDone()
+	}
+	var matched []*subscriber
+	for publish != nil || subscribe != nil {
+		select {
+		case in, open := <-publish:
+			if !open {
+				publish = nil
+				publishDone()
+				break // select
+			}
+			topic := {{.Topic}}
+			matched = matched[:0]
+			mu.Lock()
+			for s := range subs {
+				if ok, _ := path.Match(s.pattern, topic); ok {
+					matched = append(matched, s)
+				}
+			}
+			mu.Unlock()
+			for _, s := range matched {
+				s.mu.Lock()
+				if !s.closed {
+					select {
+					case s.ch <- in:
+					case <-s.done:
+						// Removed by its goroutine.
+					}
+				}
+				s.mu.Unlock()
+			}
+		case r, open := <-subscribe:
+			if !open {
+				subscribe = nil
+				break // select
+			}
+			s := &subscriber{
+				pattern: r.Pattern,
+				ch:      r.Ch,
+				done:    r.Done,
+			}
+			mu.Lock()
+			if publishing == 0 {
+				// Nothing more will be published.
+				mu.Unlock()
+				close(r.Ch)
+				break // select
+			}
+			subs[s] = struct{}{}
+			mu.Unlock()
+			go func() {
+				select {
+				case <-s.done:
+					mu.Lock()
+					remove(s)
+					mu.Unlock()
+				case <-quit:
+				}
+			}()
+		}
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("PubSub", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &PubSub{Topic: `""`}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "PubSub",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="pubsub-topic">Topic expression</label>
+					<input id="pubsub-topic" name="pubsub-topic" type="text" required title="Must be a Go expression using in" value='""'></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A PubSub part delivers each message received on publish to all the
+				subscribers interested in its topic. Subscribers can come and go
+				while the program runs.
+			</p><p>
+				The topic of each message is given by the topic expression, a Go
+				expression in terms of the message <code>in</code> that produces a
+				string, for example <code>in.Topic</code> if the messages are structs
+				with a Topic field. The default, <code>""</code>, gives every message
+				the same empty topic, which the pattern <code>*</code> matches.
+			</p><p>
+				To subscribe, send a request on subscribe. The request contains a
+				topic pattern, a channel to receive messages on, and a done channel.
+				Patterns use the syntax of <code>path.Match</code>, so for example
+				<code>sensors/*</code> matches the topic <code>sensors/kitchen</code>.
+				Messages are sent to each matching subscriber in turn, waiting for
+				each to receive it, so subscribers should use buffered channels or
+				receive promptly. A slow subscriber holds up the instance publishing
+				to it, but not subscribing or unsubscribing.
+			</p><p>
+				Once subscribed, the part owns the subscriber's channel: the part
+				closes it, and the subscriber must never close it (doing so makes the
+				part panic when it next sends a message or unsubscribes). To
+				unsubscribe, close the done channel instead, and then the part closes
+				the subscriber's channel. (The part can't tell when a send-only
+				channel has been closed, which is why unsubscribing uses a separate
+				done channel.) A subscriber with a nil done channel can't
+				unsubscribe, and stays subscribed until publish is closed.
+			</p><p>
+				When publish is closed, and the messages already received have been
+				delivered, all subscriber channels are closed. Subscription requests
+				received after publish is closed are answered by closing their
+				channel straight away. The part finishes when subscribe is closed too.
+			</p><p>
+				All instances share the same subscribers, so with multiplicity more
+				than 1, every subscriber still receives every matching message,
+				though not necessarily in the order they were published.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// PubSub is a part which delivers messages to dynamic subscribers by topic.
+type PubSub struct {
+	Topic string `json:"topic"`
+}
+
+// Clone returns a clone of this PubSub.
+func (p *PubSub) Clone() model.Part {
+	p0 := *p
+	return &p0
+}
+
+// Impl returns the PubSub implementation.
+func (p *PubSub) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Type, Topic string
+		Mult        bool
+	}{
+		Type:  n.TypeParams[pubSubTypeParam].String(),
+		Topic: p.Topic,
+		Mult:  n.Multiplicity != "1",
+	}
+	if params.Topic == "" {
+		params.Topic = `""`
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := pubSubHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute pubsub-head template: " + err.Error())
+	}
+	if err := pubSubBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute pubsub-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"path"`,
+			`"sync"`,
+		},
+		Head: h.String(),
+		Body: b.String(),
+	}
+}
+
+// Pins returns a map declaring the publish and subscribe inputs.
+func (p *PubSub) Pins() pin.Map { return pubSubPins }
+
+// TypeKey returns "PubSub".
+func (p *PubSub) TypeKey() string { return "PubSub" }
@@ -15,7 +15,9 @@
 package parts
 
 import (
+	"bytes"
 	"fmt"
+	"text/template"
 
 	"github.com/google/shenzhen-go/dev/model"
 	"github.com/google/shenzhen-go/dev/model/pin"
@@ -23,22 +25,83 @@ import (
 
 const queueTypeParam = "$Any"
 
-var queuePins = pin.NewMap(
-	&pin.Definition{
-		Name:      "input",
-		Direction: pin.Input,
-		Type:      queueTypeParam,
-	},
-	&pin.Definition{
-		Name:      "output",
-		Direction: pin.Output,
-		Type:      queueTypeParam,
-	},
-	&pin.Definition{
-		Name:      "drop",
-		Direction: pin.Output,
-		Type:      queueTypeParam,
-	},
+var (
+	queuePins = pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      queueTypeParam,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      queueTypeParam,
+		},
+		&pin.Definition{
+			Name:      "drop",
+			Direction: pin.Output,
+			Type:      queueTypeParam,
+		},
+	)
+
+	// Each item is in two heaps: one ordered by priority, to find the next
+	// item to send, and one ordered by reverse priority, to find the item
+	// to drop.
+	queuePriorityBodyTmpl = template.Must(template.New("queue-priority-body").Parse(`
+	type queueItem struct {
+		value            {{.Type}}
+		outIdx, dropIdx int
+	}
+	less := func(a, b {{.Type}}) bool {
+		return {{.Less}}
+	}
+	outHeap := &parts.Heap{
+		LessFunc:  func(x, y interface{}) bool { return less(x.(*queueItem).value, y.(*queueItem).value) },
+		IndexFunc: func(x interface{}, i int) { x.(*queueItem).outIdx = i },
+	}
+	dropHeap := &parts.Heap{
+		LessFunc:  func(x, y interface{}) bool { return less(y.(*queueItem).value, x.(*queueItem).value) },
+		IndexFunc: func(x interface{}, i int) { x.(*queueItem).dropIdx = i },
+	}
+	push := func(in {{.Type}}) {
+		it := &queueItem{value: in}
+		heap.Push(outHeap, it)
+		heap.Push(dropHeap, it)
+	}
+	for {
+		if outHeap.Len() == 0 {
+			if input == nil {
+				break
+			}
+			in, open := <-input
+			if !open {
+				break
+			}
+			push(in)
+		}
+		next := outHeap.Items[0].(*queueItem)
+		select {
+		case in, open := <-input:
+			if !open {
+				input = nil
+				break // select
+			}
+			push(in)
+			if outHeap.Len() <= maxItems {
+				break // select
+			}
+			// Drop lowest-priority item, but don't block.
+			d := heap.Pop(dropHeap).(*queueItem)
+			heap.Remove(outHeap, d.outIdx)
+			select {
+			case drop <- d.value:
+			default:
+			}
+		case output <- next.value:
+			heap.Pop(outHeap)
+			heap.Remove(dropHeap, next.dropIdx)
+		}
+	}`))
 )
 
 func init() {
@@ -63,8 +126,13 @@ func init() {
 					<select id="queue-mode" name="queue-mode">
 						<option value="lifo" selected>LIFO (stack)</option>
 						<option value="fifo">FIFO (queue)</option>
+						<option value="priority">Priority</option>
 					</select>
 				</div>
+				<div class="formfield">
+					<label for="queue-less">Priority less expression</label>
+					<input id="queue-less" name="queue-less" type="text" title="Must be a Go expression using a and b" value="a < b"></input>
+				</div>
 			</div>`,
 			},
 			{
@@ -80,10 +148,17 @@ func init() {
 			</p><p>
 				Using a LIFO
 				queue can have higher goodput than a FIFO queue.
+			</p><p>
+				Priority queues send the highest-priority item first. Priority is
+				given by the less expression, a Go expression in terms of two items
+				<code>a</code> and <code>b</code> that is true if <code>a</code>
+				should be sent before <code>b</code>, for example
+				<code>a.Priority > b.Priority</code>.
 			</p><p>
 				Queues have a required maximum number of items. If reading an item 
 				puts the queue over	the limit, the least recently read item is dropped
 				from the queue, rather than waiting for the queue to lower. 
+				Priority queues instead drop the lowest-priority item.
 				Dropped items are sent to the drop output, but unlike the main output,
 				the queue will not block on sending to drop.
 				A queue may temporarily use more memory than the limit.
@@ -99,14 +174,16 @@ type QueueMode string
 
 // Valid values of QueueMode.
 const (
-	QueueModeFIFO QueueMode = "fifo"
-	QueueModeLIFO QueueMode = "lifo"
+	QueueModeFIFO     QueueMode = "fifo"
+	QueueModeLIFO     QueueMode = "lifo"
+	QueueModePriority QueueMode = "priority"
 )
 
 // Queue is a basic queue part.
 type Queue struct {
 	Mode     QueueMode `json:"mode"`
 	MaxItems int       `json:"max_items"`
+	Less     string    `json:"less,omitempty"`
 }
 
 // Clone returns a clone of this Queue.
@@ -128,6 +205,9 @@ func (m QueueMode) params() (index, trim string) {
 
 // Impl returns the Queue implementation.
 func (q *Queue) Impl(n *model.Node) model.PartImpl {
+	if q.Mode == QueueModePriority {
+		return q.priorityImpl(n)
+	}
 	index, trim := q.Mode.params()
 	return model.PartImpl{
 		Head: fmt.Sprintf("const maxItems = %d", q.MaxItems),
@@ -173,6 +253,32 @@ func (q *Queue) Impl(n *model.Node) model.PartImpl {
 	}
 }
 
+func (q *Queue) priorityImpl(n *model.Node) model.PartImpl {
+	params := struct{ Type, Less string }{
+		Type: n.TypeParams[queueTypeParam].String(),
+		Less: q.Less,
+	}
+	if params.Less == "" {
+		params.Less = "a < b"
+	}
+	b := bytes.NewBuffer(nil)
+	if err := queuePriorityBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute queue-priority-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"container/heap"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Head: fmt.Sprintf("const maxItems = %d", q.MaxItems),
+		Body: b.String(),
+		Tail: `close(output)
+		if drop != nil {
+			close(drop)
+		}`,
+	}
+}
+
 // Pins returns a map declaring an input and two outputs of the same arbitrary type.
 func (q *Queue) Pins() pin.Map { return queuePins }
 
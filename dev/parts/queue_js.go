@@ -21,6 +21,7 @@ import "github.com/google/shenzhen-go/dev/dom"
 var (
 	inputQueueMaxItems = doc.ElementByID("queue-maxitems")
 	selectQueueMode    = doc.ElementByID("queue-mode")
+	inputQueueLess     = doc.ElementByID("queue-less")
 
 	focusedQueue *Queue
 )
@@ -32,10 +33,14 @@ func init() {
 	selectQueueMode.AddEventListener("change", func(dom.Object) {
 		focusedQueue.Mode = QueueMode(selectQueueMode.Get("value").String())
 	})
+	inputQueueLess.AddEventListener("change", func(dom.Object) {
+		focusedQueue.Less = inputQueueLess.Get("value").String()
+	})
 }
 
 func (q *Queue) GainFocus() {
 	focusedQueue = q
 	inputQueueMaxItems.Set("value", q.MaxItems)
 	selectQueueMode.Set("value", q.Mode)
+	inputQueueLess.Set("value", q.Less)
 }
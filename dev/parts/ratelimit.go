@@ -0,0 +1,286 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const rateLimitTypeParam = "$Any"
+
+var (
+	rateLimitPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      rateLimitTypeParam,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      rateLimitTypeParam,
+		},
+		&pin.Definition{
+			Name:      "drop",
+			Direction: pin.Output,
+			Type:      rateLimitTypeParam,
+		},
+	)
+
+	// The token bucket is shared by all instances.
+	rateLimitHeadTmpl = template.Must(template.New("ratelimit-head").Parse(`
+	const (
+		rate  = {{.Rate}} // tokens per second
+		burst = {{.Burst}}
+	)
+	{{if .Mult}}var mu sync.Mutex{{end}}
+	tokens, last := float64(burst), time.Now()
+
+	// reserve takes a token if one is available and returns 0, otherwise
+	// it returns how long until the next token will be available.
+	reserve := func() time.Duration {
+		{{if .Mult}}mu.Lock()
+		defer mu.Unlock()
+		{{end -}}
+		now := time.Now()
+		tokens += now.Sub(last).Seconds() * rate
+		if tokens > burst {
+			tokens = burst
+		}
+		last = now
+		if tokens >= 1 {
+			tokens--
+			return 0
+		}
+		return time.Duration((1 - tokens) / rate * float64(time.Second))
+	}`))
+
+	rateLimitBodyTmpl = template.Must(template.New("ratelimit-body").Parse(`
+	{{if eq .Mode "limit" -}}
+	for in := range input {
+		for d := reserve(); d > 0; d = reserve() {
+			time.Sleep(d)
+		}
+		output <- in
+	}
+	{{- else if eq .Mode "throttle" -}}
+	for in := range input {
+		if reserve() == 0 {
+			output <- in
+			continue
+		}
+		// Drop the excess value, but don't block.
+		select {
+		case drop <- in:
+		default:
+		}
+	}
+	{{- else if eq .Mode "debounce" -}}
+	const quiet = {{.QuietNanos}} // {{.Quiet}}
+	var pending {{.Type}}
+	havePending := false
+	timer := time.NewTimer(quiet)
+	if !timer.Stop() {
+		<-timer.C
+	}
+debounceLoop:
+	for {
+		select {
+		case in, open := <-input:
+			if !open {
+				break debounceLoop
+			}
+			if havePending {
+				// Superseded before the quiet period ended, but don't block.
+				select {
+				case drop <- pending:
+				default:
+				}
+			}
+			pending, havePending = in, true
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(quiet)
+		case <-timer.C:
+			output <- pending
+			havePending = false
+		}
+	}
+	timer.Stop()
+	if havePending {
+		output <- pending
+	}
+	{{- end}}`))
+)
+
+func init() {
+	model.RegisterPartType("RateLimit", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &RateLimit{
+				Mode:  RateLimitModeLimit,
+				Rate:  10,
+				Burst: 1,
+				Quiet: 100 * time.Millisecond,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Rate limit",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="ratelimit-mode">Mode</label>
+					<select id="ratelimit-mode" name="ratelimit-mode">
+						<option value="limit" selected>Limit (delay excess)</option>
+						<option value="throttle">Throttle (drop excess)</option>
+						<option value="debounce">Debounce (last after quiet period)</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="ratelimit-rate">Rate (per second)</label>
+					<input id="ratelimit-rate" name="ratelimit-rate" type="number" step="any" min="0.001" required title="Must be a number greater than 0." value="10"></input>
+				</div>
+				<div class="formfield">
+					<label for="ratelimit-burst">Burst</label>
+					<input id="ratelimit-burst" name="ratelimit-burst" type="number" required title="Must be a whole number, at least 1." value="1"></input>
+				</div>
+				<div class="formfield">
+					<label for="ratelimit-quiet">Quiet period (debounce)</label>
+					<input id="ratelimit-quiet" name="ratelimit-quiet" type="text" required title="Must be a parseable time.Duration" value="100ms"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A RateLimit part passes values from input to output, controlling
+				the rate at which they are sent.
+			</p><p>
+				In Limit and Throttle modes, the rate is controlled with a token bucket.
+				The bucket fills at the configured rate, up to the burst size, and each
+				value sent uses one token. In Limit mode, values wait until a token is
+				available. In Throttle mode, values that arrive when the bucket is empty
+				are sent to the drop output instead. The rate must be greater than 0;
+				a rate of 0 or less is treated as 1 per second.
+				The bucket is shared by all instances, so the total rate does not
+				depend on multiplicity.
+			</p><p>
+				In Debounce mode, a value is only sent once the input has been quiet
+				for the quiet period, and values that are superseded within the quiet
+				period are sent to the drop output instead. Rate and burst are not used.
+				When the input is closed, any pending value is sent immediately.
+				Each instance debounces independently.
+			</p><p>
+				Like a Queue, the part will not block on sending to drop.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// RateLimitMode describes how a RateLimit handles excess values.
+type RateLimitMode string
+
+// Valid values of RateLimitMode.
+const (
+	RateLimitModeLimit    RateLimitMode = "limit"
+	RateLimitModeThrottle RateLimitMode = "throttle"
+	RateLimitModeDebounce RateLimitMode = "debounce"
+)
+
+// RateLimit is a part which passes values through at a controlled rate.
+type RateLimit struct {
+	Mode  RateLimitMode `json:"mode"`
+	Rate  float64       `json:"rate"`
+	Burst uint          `json:"burst"`
+	Quiet time.Duration `json:"quiet,omitempty"`
+}
+
+// Clone returns a clone of this RateLimit.
+func (r *RateLimit) Clone() model.Part {
+	r0 := *r
+	return &r0
+}
+
+// Impl returns the RateLimit implementation.
+func (r *RateLimit) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Mode       RateLimitMode
+		Rate       float64
+		Burst      uint
+		Quiet      time.Duration
+		QuietNanos int64
+		Type       string
+		Mult       bool
+	}{
+		Mode:       r.Mode,
+		Rate:       r.Rate,
+		Burst:      r.Burst,
+		Quiet:      r.Quiet,
+		QuietNanos: int64(r.Quiet),
+		Type:       n.TypeParams[rateLimitTypeParam].String(),
+		Mult:       n.Multiplicity != "1",
+	}
+	if params.Burst < 1 {
+		params.Burst = 1
+	}
+	if !(params.Rate > 0) {
+		// A rate of 0 would make the wait for a token infinite.
+		params.Rate = 1
+	}
+	switch r.Mode {
+	case RateLimitModeLimit, RateLimitModeThrottle, RateLimitModeDebounce:
+	default:
+		panic("unknown mode " + r.Mode)
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if r.Mode != RateLimitModeDebounce {
+		if err := rateLimitHeadTmpl.Execute(h, params); err != nil {
+			panic("couldn't execute ratelimit-head template: " + err.Error())
+		}
+	}
+	if err := rateLimitBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute ratelimit-body template: " + err.Error())
+	}
+	imps := []string{`"time"`}
+	if params.Mult && r.Mode != RateLimitModeDebounce {
+		imps = append(imps, `"sync"`)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `close(output)
+		if drop != nil {
+			close(drop)
+		}`,
+	}
+}
+
+// Pins returns a map declaring an input and two outputs of the same arbitrary type.
+func (r *RateLimit) Pins() pin.Map { return rateLimitPins }
+
+// TypeKey returns "RateLimit".
+func (r *RateLimit) TypeKey() string { return "RateLimit" }
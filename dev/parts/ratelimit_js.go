@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	selectRateLimitMode = doc.ElementByID("ratelimit-mode")
+	inputRateLimitRate  = doc.ElementByID("ratelimit-rate")
+	inputRateLimitBurst = doc.ElementByID("ratelimit-burst")
+	inputRateLimitQuiet = doc.ElementByID("ratelimit-quiet")
+
+	focusedRateLimit *RateLimit
+)
+
+func init() {
+	selectRateLimitMode.AddEventListener("change", func(dom.Object) {
+		focusedRateLimit.Mode = RateLimitMode(selectRateLimitMode.Get("value").String())
+	})
+	inputRateLimitRate.AddEventListener("change", func(dom.Object) {
+		focusedRateLimit.Rate = inputRateLimitRate.Get("value").Float()
+	})
+	inputRateLimitBurst.AddEventListener("change", func(dom.Object) {
+		focusedRateLimit.Burst = uint(inputRateLimitBurst.Get("value").Int())
+	})
+	inputRateLimitQuiet.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedRateLimit.Quiet = t
+	}))
+}
+
+func (r *RateLimit) GainFocus() {
+	focusedRateLimit = r
+	selectRateLimitMode.Set("value", r.Mode)
+	inputRateLimitRate.Set("value", r.Rate)
+	inputRateLimitBurst.Set("value", r.Burst)
+	inputRateLimitQuiet.Set("value", r.Quiet.String())
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"strings"
+	"testing"
+)
+
+// The feed node sends two values straight away. With a burst of 1, the
+// second should wait for a token.
+const rateLimitZeroRateGraph = `{
+	"name": "ratelimitzero",
+	"package_path": "ratelimitzero",
+	"is_command": true,
+	"nodes": {
+		"feed": {
+			"part": {
+				"imports": [],
+				"head": [""],
+				"body": [
+					"output <- 1",
+					"output <- 2",
+					"close(output)"
+				],
+				"tail": [""],
+				"pins": {
+					"output": {"type": "int", "dir": "out"}
+				}
+			},
+			"part_type": "Code",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"output": "c0"}
+		},
+		"limit": {
+			"part": {"mode": "limit", "rate": 0, "burst": 1},
+			"part_type": "RateLimit",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"input": "c0", "output": "c1"}
+		},
+		"print": {
+			"part": {
+				"imports": ["\"fmt\"", "\"time\""],
+				"head": ["start := time.Now()"],
+				"body": ["for in := range input { fmt.Println(in, time.Since(start) >= 500*time.Millisecond) }"],
+				"tail": [""],
+				"pins": {
+					"input": {"type": "int", "dir": "in"}
+				}
+			},
+			"part_type": "Code",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"input": "c1"}
+		}
+	},
+	"channels": {"c0": {"cap": 0}, "c1": {"cap": 0}}
+}`
+
+func TestRateLimitZeroRateStillLimits(t *testing.T) {
+	got := strings.TrimSpace(goRunGraph(t, rateLimitZeroRateGraph))
+	want := "1 false\n2 true"
+	if got != want {
+		t.Errorf("go run output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+func failedType(in string) string {
+	return fmt.Sprintf("struct{ Input %s; Err error }", in)
+}
+
+var retryBodyTmpl = template.Must(template.New("retry-body").Parse(`
+	op := func(input {{.InType}}) (output {{.OutType}}, err error) {
+		{{.Body}}
+	}
+	for in := range input {
+		var out {{.OutType}}
+		var err error
+		backoff := time.Duration(initialBackoff)
+		for attempt := 1; ; attempt++ {
+			out, err = op(in)
+			if err == nil || attempt >= maxAttempts {
+				break
+			}
+			{{if .Jitter -}}
+			time.Sleep(time.Duration(float64(backoff) * (1 - jitter*rand.Float64())))
+			{{- else -}}
+			time.Sleep(backoff)
+			{{- end}}
+			backoff = time.Duration(float64(backoff) * multiplier)
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		if err != nil {
+			if failed != nil {
+				failed <- {{.FailedType}}{Input: in, Err: err}
+			}
+			continue
+		}
+		output <- out
+	}`))
+
+func init() {
+	model.RegisterPartType("Retry", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Retry{
+				InputType:      "$In",
+				OutputType:     "$Out",
+				Body:           []string{"return output, nil"},
+				MaxAttempts:    3,
+				InitialBackoff: 100 * time.Millisecond,
+				MaxBackoff:     10 * time.Second,
+				Multiplier:     2,
+				Jitter:         0.2,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Retry",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="retry-maxattempts">Max attempts</label>
+					<input id="retry-maxattempts" name="retry-maxattempts" type="number" required title="Must be a whole number, at least 1." value="3"></input>
+				</div>
+				<div class="formfield">
+					<label for="retry-initialbackoff">Initial backoff</label>
+					<input id="retry-initialbackoff" name="retry-initialbackoff" type="text" required title="Must be a parseable time.Duration" value="100ms"></input>
+				</div>
+				<div class="formfield">
+					<label for="retry-maxbackoff">Max backoff</label>
+					<input id="retry-maxbackoff" name="retry-maxbackoff" type="text" required title="Must be a parseable time.Duration" value="10s"></input>
+				</div>
+				<div class="formfield">
+					<label for="retry-multiplier">Backoff multiplier</label>
+					<input id="retry-multiplier" name="retry-multiplier" type="number" step="any" min="1" required title="Must be a number, at least 1." value="2"></input>
+				</div>
+				<div class="formfield">
+					<label for="retry-jitter">Jitter</label>
+					<input id="retry-jitter" name="retry-jitter" type="number" step="any" min="0" max="1" required title="Must be a number between 0 and 1." value="0.2"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Types",
+				Editor: `<div class="form">
+					<div class="formfield">
+						<label for="retry-inputtype">Input type</label>
+						<input id="retry-inputtype" name="retry-inputtype" type="text"></input>
+					</div>
+					<div class="formfield">
+						<label for="retry-outputtype">Output type</label>
+						<input id="retry-outputtype" name="retry-outputtype" type="text"></input>
+					</div>
+				</div>`,
+			},
+			{
+				Name:   "Imports",
+				Editor: `<div class="codeedit" id="retry-imports"></div>`,
+			},
+			{
+				Name: "Operation",
+				Editor: `<div class="formfield">
+					<span class="link" id="retry-format-link">Format</span>
+				</div>
+				<div class="codeedit formfield" id="retry-body"></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Retry part performs an operation on each input, retrying the operation
+				if it fails. The operation is BYO code.
+			</p><p>
+				The operation is the body of a function with the signature
+				<code>func(input $In) (output $Out, err error)</code>. If it returns
+				a nil error, the output is sent on the output pin. Otherwise it is
+				retried, up to the maximum number of attempts. If the last attempt
+				fails, the input and the last error are sent on the failed pin.
+				The results are named, so the body can end with a bare
+				<code>return</code>, but it must end with a return of some kind.
+			</p><p>
+				Between attempts, the part waits for a backoff duration. The first
+				backoff is the initial backoff, and each subsequent backoff is
+				multiplied by the multiplier, up to the max backoff. Jitter randomly
+				shortens each wait by up to that fraction of the backoff, so that
+				many failing operations don't all retry at once.
+			</p><p>
+				Multiplicity affects how many operations are attempted concurrently.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// Retry is a part which performs an operation on each input,
+// retrying with exponential backoff if it fails.
+type Retry struct {
+	Imports        []string      `json:"imports"`
+	Body           []string      `json:"body"`
+	InputType      string        `json:"input_type"`
+	OutputType     string        `json:"output_type"`
+	MaxAttempts    uint          `json:"max_attempts"`
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+	Multiplier     float64       `json:"multiplier"`
+	Jitter         float64       `json:"jitter"`
+}
+
+// Clone returns a clone of this Retry.
+func (r *Retry) Clone() model.Part {
+	r0 := *r
+	r0.Imports = append([]string(nil), r.Imports...)
+	r0.Body = append([]string(nil), r.Body...)
+	return &r0
+}
+
+// Impl returns the Retry implementation.
+func (r *Retry) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		InType, OutType, FailedType, Body string
+		Jitter                            bool
+	}{
+		InType:  n.PinTypes["input"].String(),
+		OutType: n.PinTypes["output"].String(),
+		Body:    strings.Join(r.Body, "\n"),
+		Jitter:  r.Jitter > 0,
+	}
+	params.FailedType = failedType(params.InType)
+	if strings.TrimSpace(params.Body) == "" {
+		// op has results, so it needs at least a return.
+		params.Body = "return output, nil"
+	}
+
+	hb, bb := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	maxAttempts := r.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	multiplier := r.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	fmt.Fprintf(hb, `const (
+		maxAttempts    = %d
+		initialBackoff = %d // %v
+		maxBackoff     = %d // %v
+		multiplier     = %v
+	`, maxAttempts, r.InitialBackoff, r.InitialBackoff, r.MaxBackoff, r.MaxBackoff, multiplier)
+	if params.Jitter {
+		fmt.Fprintf(hb, "jitter = %v\n", r.Jitter)
+	}
+	hb.WriteString(")")
+	if err := retryBodyTmpl.Execute(bb, params); err != nil {
+		panic("couldn't execute retry-body template: " + err.Error())
+	}
+	imps := append([]string{`"time"`}, r.Imports...)
+	if params.Jitter {
+		imps = append(imps, `"math/rand"`)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    hb.String(),
+		Body:    bb.String(),
+		Tail: `close(output)
+		if failed != nil {
+			close(failed)
+		}`,
+	}
+}
+
+// Pins returns a map declaring an input, an output, and a failed output.
+func (r *Retry) Pins() pin.Map {
+	return pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      r.InputType,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      r.OutputType,
+		},
+		&pin.Definition{
+			Name:      "failed",
+			Direction: pin.Output,
+			Type:      failedType(r.InputType),
+		},
+	)
+}
+
+// TypeKey returns "Retry".
+func (r *Retry) TypeKey() string { return "Retry" }
@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	retryImportsSession, retryBodySession *dom.AceSession
+
+	inputRetryMaxAttempts    = doc.ElementByID("retry-maxattempts")
+	inputRetryInitialBackoff = doc.ElementByID("retry-initialbackoff")
+	inputRetryMaxBackoff     = doc.ElementByID("retry-maxbackoff")
+	inputRetryMultiplier     = doc.ElementByID("retry-multiplier")
+	inputRetryJitter         = doc.ElementByID("retry-jitter")
+	inputRetryInputType      = doc.ElementByID("retry-inputtype")
+	inputRetryOutputType     = doc.ElementByID("retry-outputtype")
+	linkRetryFormat          = doc.ElementByID("retry-format-link")
+
+	focusedRetry *Retry
+)
+
+func init() {
+	retryImportsSession = setupAce("retry-imports", dom.AceGoMode, retryImportsChange)
+	retryBodySession = setupAce("retry-body", dom.AceGoMode, retryBodyChange)
+
+	inputRetryMaxAttempts.AddEventListener("change", func(dom.Object) {
+		focusedRetry.MaxAttempts = uint(inputRetryMaxAttempts.Get("value").Int())
+	})
+	inputRetryInitialBackoff.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedRetry.InitialBackoff = t
+	}))
+	inputRetryMaxBackoff.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedRetry.MaxBackoff = t
+	}))
+	inputRetryMultiplier.AddEventListener("change", func(dom.Object) {
+		focusedRetry.Multiplier = inputRetryMultiplier.Get("value").Float()
+	})
+	inputRetryJitter.AddEventListener("change", func(dom.Object) {
+		focusedRetry.Jitter = inputRetryJitter.Get("value").Float()
+	})
+	inputRetryInputType.AddEventListener("change", func(dom.Object) {
+		focusedRetry.InputType = inputRetryInputType.Get("value").String()
+	})
+	inputRetryOutputType.AddEventListener("change", func(dom.Object) {
+		focusedRetry.OutputType = inputRetryOutputType.Get("value").String()
+	})
+	linkRetryFormat.AddEventListener("click", formatHandler(retryBodySession))
+}
+
+func retryImportsChange(dom.Object) {
+	focusedRetry.Imports = stripCR(strings.Split(retryImportsSession.Value(), "\n"))
+}
+
+func retryBodyChange(dom.Object) {
+	focusedRetry.Body = stripCR(strings.Split(retryBodySession.Value(), "\n"))
+}
+
+func (r *Retry) GainFocus() {
+	focusedRetry = r
+	inputRetryMaxAttempts.Set("value", r.MaxAttempts)
+	inputRetryInitialBackoff.Set("value", r.InitialBackoff.String())
+	inputRetryMaxBackoff.Set("value", r.MaxBackoff.String())
+	inputRetryMultiplier.Set("value", r.Multiplier)
+	inputRetryJitter.Set("value", r.Jitter)
+	inputRetryInputType.Set("value", r.InputType)
+	inputRetryOutputType.Set("value", r.OutputType)
+	retryImportsSession.SetValue(strings.Join(r.Imports, "\n"))
+	retryBodySession.SetValue(strings.Join(r.Body, "\n"))
+}
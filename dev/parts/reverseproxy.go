@@ -0,0 +1,254 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	reverseProxyPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "requests",
+			Direction: pin.Input,
+			Type:      "*parts.HTTPRequest",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+
+	reverseProxyHeadTmpl = template.Must(template.New("reverseproxy-head").Parse(`
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConnsPerHost: multiplicity,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	var roundTripper http.RoundTripper = transport
+	{{if .Prometheus -}}
+	roundTripper = promhttp.InstrumentRoundTripperDuration(
+		reverseProxyUpstreamDuration.MustCurryWith(prometheus.Labels{"node_name": "{{.NodeName}}"}),
+		roundTripper,
+	)
+	{{end -}}
+	onError := func(req *http.Request, err error) {
+		if errors != nil {
+			errors <- fmt.Errorf("proxying %s %s: %v", req.Method, req.URL, err)
+		}
+	}
+	{{if not .Target -}}
+	// The upstream is fixed, so one proxy will do.
+	var proxy *httputil.ReverseProxy
+	if upstream, err := url.Parse({{printf "%q" .Upstream}}); err != nil {
+		if errors != nil {
+			errors <- fmt.Errorf("parsing upstream URL: %v", err)
+		}
+	} else {
+		proxy = parts.NewReverseProxy(upstream, {{.PreserveHost}}, roundTripper, {{.FlushIntervalNanos}}, onError) // {{.FlushInterval}}
+	}
+	{{end -}}`))
+
+	reverseProxyBodyTmpl = template.Must(template.New("reverseproxy-body").Parse(`
+	for r := range requests {
+		{{if .Target -}}
+		target := {{.Target}}
+		upstream, err := url.Parse(target)
+		if err != nil {
+			if errors != nil {
+				errors <- fmt.Errorf("parsing upstream URL %q for %s %s: %v", target, r.Request.Method, r.Request.URL, err)
+			}
+			http.Error(r, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			r.Close()
+			continue
+		}
+		proxy := parts.NewReverseProxy(upstream, {{.PreserveHost}}, roundTripper, {{.FlushIntervalNanos}}, onError) // {{.FlushInterval}}
+		{{else -}}
+		if proxy == nil {
+			// The bad upstream URL has already been reported.
+			http.Error(r, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			r.Close()
+			continue
+		}
+		{{end -}}
+		proxy.ServeHTTP(r.ResponseWriter, r.Request)
+		r.Close()
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("ReverseProxy", "Web", &model.PartType{
+		New: func() model.Part {
+			return &ReverseProxy{Upstream: "http://localhost:8080"}
+		},
+		Init: `
+		var reverseProxyUpstreamDuration = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "shenzhen_go",
+				Subsystem: "reverse_proxy",
+				Name:      "upstream_duration_seconds",
+				Help:      "Durations of upstream requests made by ReverseProxy nodes",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"node_name", "code", "method"},
+		)
+
+		func init() {
+			prometheus.MustRegister(reverseProxyUpstreamDuration)
+		}
+		`,
+		Panels: []model.PartPanel{
+			{
+				Name: "Proxy",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="reverseproxy-upstream">Upstream URL</label>
+					<input id="reverseproxy-upstream" name="reverseproxy-upstream" type="text"></input>
+				</div>
+				<div class="formfield">
+					<label for="reverseproxy-target">Upstream URL expression</label>
+					<input id="reverseproxy-target" name="reverseproxy-target" type="text" title="Optional Go expression of type string, using r (a *parts.HTTPRequest)"></input>
+				</div>
+				<div class="formfield">
+					<input id="reverseproxy-preservehost" name="reverseproxy-preservehost" type="checkbox"></input>
+					<label for="reverseproxy-preservehost">Preserve Host header</label>
+				</div>
+				<div class="formfield">
+					<label for="reverseproxy-flushinterval">Flush interval</label>
+					<input id="reverseproxy-flushinterval" name="reverseproxy-flushinterval" type="text" required title="Must be a parseable time.Duration" value="0s"></input>
+				</div>
+				<div class="formfield">
+					<input id="reverseproxy-enableprometheus" name="reverseproxy-enableprometheus" type="checkbox"></input>
+					<label for="reverseproxy-enableprometheus">Enable Prometheus metrics</label>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A ReverseProxy part forwards each request to an upstream server, using
+				<code>httputil.ReverseProxy</code>, copies the upstream response back,
+				and closes the request.
+			</p><p>
+				The path of each request is appended to the path of the upstream URL,
+				and the query parameters are combined. If an upstream URL expression is
+				given, it is evaluated for each request (which is available as
+				<code>r</code>, so the expression can use <code>r.Request</code> and
+				<code>r.Params</code>) and used instead of the fixed upstream URL.
+			</p><p>
+				Unless the Host header is preserved, it is set to the upstream host.
+				A flush interval of zero means the response is only flushed at the
+				end, which is fine except for streaming responses.
+			</p><p>
+				If the upstream URL is invalid or the upstream can't be reached, the
+				response is a 502 error, and the error is sent to the errors output,
+				if it is connected. A fixed upstream URL is only parsed once, so if
+				it is invalid the error is only sent once, and every request gets a
+				502 error.
+				With Prometheus metrics enabled, a histogram of upstream request
+				durations is exported, labelled with the method and response code.
+			</p><p>
+				Multiplicity limits the number of requests proxied at once.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// ReverseProxy is a part which forwards requests to an upstream server.
+type ReverseProxy struct {
+	Upstream         string        `json:"upstream,omitempty"`
+	Target           string        `json:"target,omitempty"`
+	PreserveHost     bool          `json:"preserve_host,omitempty"`
+	FlushInterval    time.Duration `json:"flush_interval,omitempty"`
+	EnablePrometheus bool          `json:"enable_prometheus"`
+}
+
+// Clone returns a clone of this ReverseProxy.
+func (p *ReverseProxy) Clone() model.Part {
+	p0 := *p
+	return &p0
+}
+
+// Impl returns the ReverseProxy implementation.
+func (p *ReverseProxy) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Upstream, Target   string
+		PreserveHost       bool
+		FlushInterval      time.Duration
+		FlushIntervalNanos int64
+		Prometheus         bool
+		NodeName           string
+	}{
+		Upstream:           p.Upstream,
+		Target:             p.Target,
+		PreserveHost:       p.PreserveHost,
+		FlushInterval:      p.FlushInterval,
+		FlushIntervalNanos: int64(p.FlushInterval),
+		Prometheus:         p.EnablePrometheus,
+		NodeName:           n.Name,
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := reverseProxyHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute reverseproxy-head template: " + err.Error())
+	}
+	if err := reverseProxyBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute reverseproxy-body template: " + err.Error())
+	}
+	imps := []string{
+		`"fmt"`,
+		`"net"`,
+		`"net/http"`,
+		`"net/url"`,
+		`"time"`,
+		`"github.com/google/shenzhen-go/dev/parts"`,
+	}
+	if p.Target == "" {
+		imps = append(imps, `"net/http/httputil"`)
+	}
+	if p.EnablePrometheus {
+		imps = append(imps,
+			`"github.com/prometheus/client_golang/prometheus"`,
+			`"github.com/prometheus/client_golang/prometheus/promhttp"`,
+		)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `transport.CloseIdleConnections()
+		if errors != nil {
+			close(errors)
+		}`,
+		NeedsInit: p.EnablePrometheus,
+	}
+}
+
+// Pins returns a map declaring a request input and an errors output.
+func (p *ReverseProxy) Pins() pin.Map { return reverseProxyPins }
+
+// TypeKey returns "ReverseProxy".
+func (p *ReverseProxy) TypeKey() string { return "ReverseProxy" }
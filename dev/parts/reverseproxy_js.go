@@ -0,0 +1,70 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	reverseProxyOutlets = struct {
+		inputUpstream         dom.Element
+		inputTarget           dom.Element
+		inputPreserveHost     dom.Element
+		inputFlushInterval    dom.Element
+		inputEnablePrometheus dom.Element
+	}{
+		inputUpstream:         doc.ElementByID("reverseproxy-upstream"),
+		inputTarget:           doc.ElementByID("reverseproxy-target"),
+		inputPreserveHost:     doc.ElementByID("reverseproxy-preservehost"),
+		inputFlushInterval:    doc.ElementByID("reverseproxy-flushinterval"),
+		inputEnablePrometheus: doc.ElementByID("reverseproxy-enableprometheus"),
+	}
+
+	focusedReverseProxy *ReverseProxy
+)
+
+func init() {
+	o := &reverseProxyOutlets
+	o.inputUpstream.AddEventListener("change", func(dom.Object) {
+		focusedReverseProxy.Upstream = o.inputUpstream.Get("value").String()
+	})
+	o.inputTarget.AddEventListener("change", func(dom.Object) {
+		focusedReverseProxy.Target = o.inputTarget.Get("value").String()
+	})
+	o.inputPreserveHost.AddEventListener("change", func(dom.Object) {
+		focusedReverseProxy.PreserveHost = o.inputPreserveHost.Get("checked").Bool()
+	})
+	o.inputFlushInterval.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedReverseProxy.FlushInterval = t
+	}))
+	o.inputEnablePrometheus.AddEventListener("change", func(dom.Object) {
+		focusedReverseProxy.EnablePrometheus = o.inputEnablePrometheus.Get("checked").Bool()
+	})
+}
+
+func (p *ReverseProxy) GainFocus() {
+	focusedReverseProxy = p
+	o := &reverseProxyOutlets
+	o.inputUpstream.Set("value", p.Upstream)
+	o.inputTarget.Set("value", p.Target)
+	o.inputPreserveHost.Set("checked", p.PreserveHost)
+	o.inputFlushInterval.Set("value", p.FlushInterval.String())
+	o.inputEnablePrometheus.Set("checked", p.EnablePrometheus)
+}
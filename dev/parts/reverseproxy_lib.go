@@ -0,0 +1,81 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NewReverseProxy returns the reverse proxy used by the ReverseProxy part.
+// It sends requests to target (as ReverseProxyDirector does) using
+// transport, flushing the response every flushInterval. If proxying a
+// request fails, onError (if not nil) is called with the outgoing request
+// and the error, and the client gets a 502 response.
+func NewReverseProxy(target *url.URL, preserveHost bool, transport http.RoundTripper, flushInterval time.Duration, onError func(*http.Request, error)) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			ReverseProxyDirector(req, target, preserveHost)
+		},
+		Transport:     transport,
+		FlushInterval: flushInterval,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			if onError != nil {
+				onError(req, err)
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
+// ReverseProxyDirector rewrites an outgoing request to go to target, in the
+// same way as the director of httputil.NewSingleHostReverseProxy: the
+// request path is appended to the target path, and the queries are
+// combined. Unless preserveHost is true, the Host header is set to the
+// target host.
+func ReverseProxyDirector(req *http.Request, target *url.URL, preserveHost bool) {
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path = joinURLPath(target.Path, req.URL.Path)
+	req.URL.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+	case req.URL.RawQuery == "":
+		req.URL.RawQuery = target.RawQuery
+	default:
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	}
+	if !preserveHost {
+		req.Host = target.Host
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// Explicitly disable the default User-Agent.
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+func joinURLPath(a, b string) string {
+	aslash, bslash := strings.HasSuffix(a, "/"), strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestReverseProxyDirector(t *testing.T) {
+	tests := []struct {
+		target, req  string
+		preserveHost bool
+		wantURL      string
+		wantHost     string
+	}{
+		{"http://up:8080", "http://front/a/b?x=1", false, "http://up:8080/a/b?x=1", "up:8080"},
+		{"http://up:8080/", "http://front/a", false, "http://up:8080/a", "up:8080"},
+		{"https://up/base", "http://front/a", false, "https://up/base/a", "up"},
+		{"https://up/base/", "http://front/", false, "https://up/base/", "up"},
+		{"http://up/?k=v", "http://front/a?x=1", false, "http://up/a?k=v&x=1", "up"},
+		{"http://up/?k=v", "http://front/a", true, "http://up/a?k=v", "front"},
+	}
+	for _, test := range tests {
+		target, err := url.Parse(test.target)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) = %v", test.target, err)
+		}
+		req := httptest.NewRequest("GET", test.req, nil)
+		ReverseProxyDirector(req, target, test.preserveHost)
+		if got := req.URL.String(); got != test.wantURL {
+			t.Errorf("ReverseProxyDirector(%s, %s, %t): URL = %q, want %q", test.req, test.target, test.preserveHost, got, test.wantURL)
+		}
+		if got := req.Host; got != test.wantHost {
+			t.Errorf("ReverseProxyDirector(%s, %s, %t): Host = %q, want %q", test.req, test.target, test.preserveHost, got, test.wantHost)
+		}
+	}
+}
+
+// proxyThrough serves requests the way the ReverseProxy part does: each
+// *HTTPRequest is passed to proxy, then closed.
+func proxyThrough(proxy http.Handler) (*httptest.Server, func()) {
+	reqs := make(chan *HTTPRequest)
+	done := make(chan struct{})
+	go func() {
+		for r := range reqs {
+			proxy.ServeHTTP(r.ResponseWriter, r.Request)
+			r.Close()
+		}
+		close(done)
+	}()
+	front := httptest.NewServer(HTTPHandler(reqs))
+	return front, func() {
+		front.Close()
+		close(reqs)
+		<-done
+	}
+}
+
+func TestNewReverseProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprintf(w, "%s %s", r.URL.Path, r.URL.RawQuery)
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL + "/base?k=v")
+	if err != nil {
+		t.Fatalf("url.Parse(%q) = %v", upstream.URL+"/base?k=v", err)
+	}
+	onError := func(req *http.Request, err error) {
+		t.Errorf("onError(%s %s, %v) called", req.Method, req.URL, err)
+	}
+	front, stop := proxyThrough(NewReverseProxy(target, false, http.DefaultTransport, 0, onError))
+	defer stop()
+
+	resp, err := http.Get(front.URL + "/a/b?x=1")
+	if err != nil {
+		t.Fatalf("http.Get = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response body: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusTeapot; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if got, want := string(body), "/base/a/b k=v&x=1"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestNewReverseProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) = %v", upstream.URL, err)
+	}
+	upstream.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	onError := func(req *http.Request, err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	front, stop := proxyThrough(NewReverseProxy(target, false, http.DefaultTransport, 0, onError))
+	defer stop()
+
+	resp, err := http.Get(front.URL + "/a")
+	if err != nil {
+		t.Fatalf("http.Get = %v", err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusBadGateway; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("onError called %d times, want 1", len(errs))
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const routerTypeParam = "$Any"
+
+func init() {
+	model.RegisterPartType("Router", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Router{
+				OutputNum: 2,
+				Strategy:  RouteRoundRobin,
+				Key:       "in",
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Router",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label>Number of outputs: <input id="router-outputnum" type="number"></input></label>
+				</div>
+				<div class="formfield">
+					<label for="router-strategy">Strategy</label>
+					<select id="router-strategy" name="router-strategy">
+						<option value="roundrobin" selected>Round-robin</option>
+						<option value="hash">Consistent hash of key</option>
+						<option value="leastloaded">Least loaded output</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="router-key">Key expression</label>
+					<input id="router-key" name="router-key" type="text" required title="Must be a Go expression using in" value="in"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Router part sends every input value to exactly one of the outputs.
+				The number of outputs is configurable, as is the strategy for
+				choosing the output.
+			</p><p>
+				Round-robin sends values to each output in turn.
+			</p><p>
+				Consistent hash evaluates the key expression (a Go expression in
+				terms of the input value <code>in</code>, for example
+				<code>in.UserID</code>) and hashes the result, so that values with
+				the same key are always sent to the same output. This is useful for
+				sharding values between stateful nodes, such as Caches.
+				Keys are hashed using their default format (as with
+				<code>fmt.Sprint</code>).
+			</p><p>
+				Least loaded sends each value to the output with the fewest
+				values waiting in its channel buffer. It is only useful
+				when the outputs are attached to buffered channels.
+			</p><p>
+				Outputs that are not connected are never chosen.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// RouterStrategy describes how a Router chooses an output.
+type RouterStrategy string
+
+// Valid values of RouterStrategy.
+const (
+	RouteRoundRobin  RouterStrategy = "roundrobin"
+	RouteHash        RouterStrategy = "hash"
+	RouteLeastLoaded RouterStrategy = "leastloaded"
+)
+
+// Router is a part that sends each input value to one of a configurable
+// number of outputs.
+type Router struct {
+	OutputNum uint           `json:"output_num"`
+	Strategy  RouterStrategy `json:"strategy"`
+	Key       string         `json:"key,omitempty"`
+}
+
+// Clone returns a clone of this part.
+func (r *Router) Clone() model.Part {
+	r0 := *r
+	return &r0
+}
+
+// Impl returns the implementation.
+func (r *Router) Impl(n *model.Node) model.PartImpl {
+	var outs []string
+	tb := bytes.NewBuffer(nil)
+	for i := uint(0); i < r.OutputNum; i++ {
+		o := fmt.Sprintf("output%d", i)
+		if n.Connections[o] == "nil" {
+			// We know at design time whether a pin is nil.
+			continue
+		}
+		outs = append(outs, o)
+		fmt.Fprintf(tb, "close(%s)\n", o)
+	}
+	if len(outs) == 0 {
+		return model.PartImpl{Body: "for range input {}"}
+	}
+
+	var imps []string
+	bb := bytes.NewBuffer(nil)
+	fmt.Fprintf(bb, "outputs := []chan<- %s{%s}\n", n.TypeParams[routerTypeParam], strings.Join(outs, ", "))
+	switch r.Strategy {
+	case RouteRoundRobin:
+		bb.WriteString(`next := 0
+		for in := range input {
+			outputs[next] <- in
+			next = (next + 1) % len(outputs)
+		}`)
+	case RouteHash:
+		key := r.Key
+		if key == "" {
+			key = "in"
+		}
+		fmt.Fprintf(bb, `for in := range input {
+			outputs[parts.ConsistentHash(%s, len(outputs))] <- in
+		}`, key)
+		imps = append(imps, `"github.com/google/shenzhen-go/dev/parts"`)
+	case RouteLeastLoaded:
+		// Start the search at a different output each time, so that ties
+		// (e.g. unbuffered channels) are broken round-robin.
+		bb.WriteString(`next := 0
+		for in := range input {
+			idx := next
+			for i := range outputs {
+				j := (next + i) % len(outputs)
+				if len(outputs[j]) < len(outputs[idx]) {
+					idx = j
+				}
+			}
+			next = (next + 1) % len(outputs)
+			outputs[idx] <- in
+		}`)
+	default:
+		panic("unknown strategy " + r.Strategy)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    bb.String(),
+		Tail:    tb.String(),
+	}
+}
+
+// Pins returns a map with one input and N outputs.
+func (r *Router) Pins() pin.Map {
+	m := pin.NewMap(&pin.Definition{
+		Name:      "input",
+		Direction: pin.Input,
+		Type:      routerTypeParam,
+	})
+	for i := uint(0); i < r.OutputNum; i++ {
+		n := fmt.Sprintf("output%d", i)
+		m[n] = &pin.Definition{
+			Name:      n,
+			Direction: pin.Output,
+			Type:      routerTypeParam,
+		}
+	}
+	return m
+}
+
+// TypeKey returns "Router".
+func (r *Router) TypeKey() string { return "Router" }
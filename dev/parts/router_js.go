@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import "github.com/google/shenzhen-go/dev/dom"
+
+var (
+	inputRouterOutputNum = doc.ElementByID("router-outputnum")
+	selectRouterStrategy = doc.ElementByID("router-strategy")
+	inputRouterKey       = doc.ElementByID("router-key")
+
+	focusedRouter *Router
+)
+
+func init() {
+	inputRouterOutputNum.AddEventListener("change", func(dom.Object) {
+		focusedRouter.OutputNum = uint(inputRouterOutputNum.Get("value").Int())
+	})
+	selectRouterStrategy.AddEventListener("change", func(dom.Object) {
+		focusedRouter.Strategy = RouterStrategy(selectRouterStrategy.Get("value").String())
+	})
+	inputRouterKey.AddEventListener("change", func(dom.Object) {
+		focusedRouter.Key = inputRouterKey.Get("value").String()
+	})
+}
+
+func (r *Router) GainFocus() {
+	focusedRouter = r
+	inputRouterOutputNum.Set("value", r.OutputNum)
+	selectRouterStrategy.Set("value", r.Strategy)
+	inputRouterKey.Set("value", r.Key)
+}
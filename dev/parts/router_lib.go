@@ -0,0 +1,40 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ConsistentHash maps a key to one of a number of buckets, such that
+// changing the number of buckets moves as few keys as possible.
+// The key is hashed using its default format (as with fmt.Sprint),
+// so keys that print the same always map to the same bucket.
+//
+// It uses the "jump" consistent hash algorithm of Lamping and Veach
+// (https://arxiv.org/abs/1406.2294).
+func ConsistentHash(key interface{}, buckets int) int {
+	h := fnv.New64a()
+	fmt.Fprint(h, key)
+	k := h.Sum64()
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
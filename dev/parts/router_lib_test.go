@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import "testing"
+
+func TestConsistentHash(t *testing.T) {
+	const keys = 10000
+	for buckets := 1; buckets < 10; buckets++ {
+		moved := 0
+		for k := 0; k < keys; k++ {
+			got := ConsistentHash(k, buckets)
+			if got < 0 || got >= buckets {
+				t.Fatalf("ConsistentHash(%d, %d) = %d, want in [0, %d)", k, buckets, got, buckets)
+			}
+			if again := ConsistentHash(k, buckets); again != got {
+				t.Fatalf("ConsistentHash(%d, %d) = %d then %d, want same result", k, buckets, got, again)
+			}
+			next := ConsistentHash(k, buckets+1)
+			if next == got {
+				continue
+			}
+			if next != buckets {
+				t.Errorf("ConsistentHash(%d, %d) = %d, but ConsistentHash(%d, %d) = %d; want key to stay or move to the new bucket", k, buckets, got, k, buckets+1, next)
+			}
+			moved++
+		}
+		// Roughly keys/(buckets+1) should move to the new bucket.
+		if want := keys / (buckets + 1); moved < want/2 || moved > want*2 {
+			t.Errorf("growing from %d buckets moved %d keys, want about %d", buckets, moved, want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	serverSentEventsPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "requests",
+			Direction: pin.Input,
+			Type:      "*parts.HTTPRequest",
+		},
+		&pin.Definition{
+			Name:      "events",
+			Direction: pin.Input,
+			Type:      "$T",
+		},
+	)
+
+	// The clients are shared by all instances. Each client has a goroutine
+	// writing its events, which removes the client when it disconnects.
+	// Events are never waited for: a client whose buffer is full is dropped.
+	// The last instance to finish reading events disconnects the clients.
+	serverSentEventsHeadTmpl = template.Must(template.New("serversentevents-head").Parse(`
+	type client struct {
+		r  *parts.HTTPRequest
+		ch chan {{.Type}}
+	}
+	var (
+		mu      sync.Mutex
+		writers sync.WaitGroup
+	)
+	clients := make(map[*client]struct{})
+	publishing := multiplicity
+	// remove must be called with mu held.
+	remove := func(c *client) {
+		if _, ok := clients[c]; !ok {
+			return
+		}
+		delete(clients, c)
+		close(c.ch)
+	}
+	eventsDone := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if publishing--; publishing > 0 {
+			return
+		}
+		for c := range clients {
+			remove(c)
+		}
+	}`))
+
+	serverSentEventsBodyTmpl = template.Must(template.New("serversentevents-body").Parse(`
+	{{if .Mult -}}
+	// Channels are set to nil when closed, so each instance needs its own.
+	events, requests := events, requests
+	{{end -}}
+	if events == nil {
+		eventsDone()
+	}
+	for events != nil || requests != nil {
+		select {
+		case in, open := <-events:
+			if !open {
+				events = nil
+				eventsDone()
+				break // select
+			}
+			mu.Lock()
+			for c := range clients {
+				select {
+				case c.ch <- in:
+				default:
+					// Too slow to keep up.
+					remove(c)
+				}
+			}
+			mu.Unlock()
+		case r, open := <-requests:
+			if !open {
+				requests = nil
+				break // select
+			}
+			mu.Lock()
+			if publishing == 0 {
+				// No more events, so tell the client not to reconnect.
+				mu.Unlock()
+				r.WriteHeader(http.StatusNoContent)
+				r.Close()
+				break // select
+			}
+			c := &client{
+				r:  r,
+				ch: make(chan {{.Type}}, {{.BufferSize}}),
+			}
+			clients[c] = struct{}{}
+			writers.Add(1)
+			mu.Unlock()
+			go func() {
+				defer writers.Done()
+				defer c.r.Close()
+				leave := func() {
+					mu.Lock()
+					remove(c)
+					mu.Unlock()
+				}
+				stream, err := parts.NewSSEStream(c.r.ResponseWriter)
+				if err != nil {
+					leave()
+					http.Error(c.r, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				var keepAlive <-chan time.Time
+				{{if .KeepAlive -}}
+				ticker := time.NewTicker({{.KeepAliveNanos}}) // {{.KeepAlive}}
+				defer ticker.Stop()
+				keepAlive = ticker.C
+				{{end -}}
+				for {
+					var err error
+					select {
+					case ev, open := <-c.ch:
+						if !open {
+							return
+						}
+						err = stream.Event({{printf "%q" .EventType}}, ev)
+					case <-keepAlive:
+						err = stream.Comment("")
+					case <-c.r.Request.Context().Done():
+						err = c.r.Request.Context().Err()
+					}
+					if err != nil {
+						leave()
+						return
+					}
+				}
+			}()
+		}
+	}`))
+)
+
+func init() {
+	model.RegisterPartType("ServerSentEvents", "Web", &model.PartType{
+		New: func() model.Part {
+			return &ServerSentEvents{
+				BufferSize: 16,
+				KeepAlive:  30 * time.Second,
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Events",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="serversentevents-eventtype">Event type</label>
+					<input id="serversentevents-eventtype" name="serversentevents-eventtype" type="text" title="Leave empty for the default type, message"></input>
+				</div>
+				<div class="formfield">
+					<label for="serversentevents-buffersize">Buffer size per client</label>
+					<input id="serversentevents-buffersize" name="serversentevents-buffersize" type="number" min="1" value="16"></input>
+				</div>
+				<div class="formfield">
+					<label for="serversentevents-keepalive">Keep-alive interval</label>
+					<input id="serversentevents-keepalive" name="serversentevents-keepalive" type="text" required title="Must be a parseable time.Duration" value="30s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A ServerSentEvents part streams values to HTTP clients, using
+				<a href="https://html.spec.whatwg.org/multipage/server-sent-events.html">Server-Sent Events</a>
+				(for example, to an <code>EventSource</code> in a browser).
+			</p><p>
+				Each request received on requests subscribes a client, which is sent
+				a <code>text/event-stream</code> response. Every value received on
+				events is then encoded as JSON and sent to all the connected clients,
+				as an event of the configured type.
+			</p><p>
+				Clients that disconnect are removed, and their requests closed.
+				Each client has a buffer of events waiting to be sent. If a
+				client's buffer fills up, it is disconnected rather than holding up
+				the other clients. Browsers reconnect automatically, but miss the
+				events sent in the meantime.
+			</p><p>
+				If the keep-alive interval isn't zero, a comment is sent to each
+				client that often. This keeps idle connections open through
+				proxies, and notices clients that have gone away. Note that the
+				HTTP server's write timeout, if any, limits how long each client
+				stays connected.
+			</p><p>
+				When events is closed, all clients are disconnected, and any later
+				requests are answered with 204 No Content, which tells browsers
+				to stop reconnecting.
+			</p><p>
+				All instances share the same clients, so every client receives every
+				event whatever the multiplicity, though not necessarily in the order
+				they were received.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// ServerSentEvents is a part which streams values to HTTP clients as
+// Server-Sent Events.
+type ServerSentEvents struct {
+	EventType  string        `json:"event_type,omitempty"`
+	BufferSize uint          `json:"buffer_size"`
+	KeepAlive  time.Duration `json:"keep_alive,omitempty"`
+}
+
+// Clone returns a clone of this ServerSentEvents.
+func (s *ServerSentEvents) Clone() model.Part {
+	s0 := *s
+	return &s0
+}
+
+// Impl returns the ServerSentEvents implementation.
+func (s *ServerSentEvents) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Type, EventType string
+		BufferSize      uint
+		KeepAlive       time.Duration
+		KeepAliveNanos  int64
+		Mult            bool
+	}{
+		Type:           n.TypeParams["$T"].String(),
+		EventType:      s.EventType,
+		BufferSize:     s.BufferSize,
+		KeepAlive:      s.KeepAlive,
+		KeepAliveNanos: int64(s.KeepAlive),
+		Mult:           n.Multiplicity != "1",
+	}
+	if params.BufferSize < 1 {
+		// An unbuffered client would be dropped whenever it was busy.
+		params.BufferSize = 1
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := serverSentEventsHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute serversentevents-head template: " + err.Error())
+	}
+	if err := serverSentEventsBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute serversentevents-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"net/http"`,
+			`"sync"`,
+			`"time"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Head: h.String(),
+		Body: b.String(),
+		Tail: `writers.Wait()`,
+	}
+}
+
+// Pins returns a map declaring the requests and events inputs.
+func (s *ServerSentEvents) Pins() pin.Map { return serverSentEventsPins }
+
+// TypeKey returns "ServerSentEvents".
+func (s *ServerSentEvents) TypeKey() string { return "ServerSentEvents" }
@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	inputServerSentEventsEventType  = doc.ElementByID("serversentevents-eventtype")
+	inputServerSentEventsBufferSize = doc.ElementByID("serversentevents-buffersize")
+	inputServerSentEventsKeepAlive  = doc.ElementByID("serversentevents-keepalive")
+
+	focusedServerSentEvents *ServerSentEvents
+)
+
+func init() {
+	inputServerSentEventsEventType.AddEventListener("change", func(dom.Object) {
+		focusedServerSentEvents.EventType = inputServerSentEventsEventType.Get("value").String()
+	})
+	inputServerSentEventsBufferSize.AddEventListener("change", func(dom.Object) {
+		focusedServerSentEvents.BufferSize = uint(inputServerSentEventsBufferSize.Get("value").Int())
+	})
+	inputServerSentEventsKeepAlive.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedServerSentEvents.KeepAlive = t
+	}))
+}
+
+func (s *ServerSentEvents) GainFocus() {
+	focusedServerSentEvents = s
+	inputServerSentEventsEventType.Set("value", s.EventType)
+	inputServerSentEventsBufferSize.Set("value", s.BufferSize)
+	inputServerSentEventsKeepAlive.Set("value", s.KeepAlive.String())
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SSEStream writes Server-Sent Events to a HTTP response, in the
+// text/event-stream format, flushing after each event.
+type SSEStream struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+// NewSSEStream writes the response header for an event stream to w, and
+// returns a SSEStream for sending events. It returns an error if w doesn't
+// support flushing, since events would otherwise sit in a buffer.
+func NewSSEStream(w http.ResponseWriter) (*SSEStream, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("response doesn't support streaming")
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &SSEStream{w: w, f: f}, nil
+}
+
+// Event sends an event of the given type, with the JSON encoding of v as
+// the data. An empty event type means the default, "message".
+func (s *SSEStream) Event(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b := bytes.NewBuffer(nil)
+	if event != "" {
+		writeSSEField(b, "event", []byte(sseEventNewlines.Replace(event)))
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		writeSSEField(b, "data", line)
+	}
+	b.WriteByte('\n')
+	return s.write(b.Bytes())
+}
+
+// Comment sends a comment, which clients ignore. This is useful for keeping
+// idle connections alive, and for noticing when clients have gone away.
+func (s *SSEStream) Comment(text string) error {
+	b := bytes.NewBuffer(nil)
+	for _, line := range strings.Split(sseNewlines.Replace(text), "\n") {
+		writeSSEField(b, "", []byte(line))
+	}
+	b.WriteByte('\n')
+	return s.write(b.Bytes())
+}
+
+func (s *SSEStream) write(p []byte) error {
+	if _, err := s.w.Write(p); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
+
+var (
+	// sseNewlines normalises line endings, which all end fields.
+	sseNewlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+	// sseEventNewlines replaces line endings in event types, which
+	// must fit on one line.
+	sseEventNewlines = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+)
+
+func writeSSEField(b *bytes.Buffer, name string, value []byte) {
+	b.WriteString(name)
+	b.WriteByte(':')
+	if len(value) > 0 {
+		b.WriteByte(' ')
+		b.Write(value)
+	}
+	b.WriteByte('\n')
+}
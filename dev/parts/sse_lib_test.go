@@ -0,0 +1,64 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSEStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	s, err := NewSSEStream(w)
+	if err != nil {
+		t.Fatalf("NewSSEStream() = %v", err)
+	}
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	if !w.Flushed {
+		t.Error("response not flushed after NewSSEStream")
+	}
+	if err := s.Event("", map[string]int{"a": 1}); err != nil {
+		t.Errorf("Event() = %v", err)
+	}
+	if err := s.Event("up\ndate", "x\ny"); err != nil {
+		t.Errorf("Event() = %v", err)
+	}
+	if err := s.Comment("ping\r\npong"); err != nil {
+		t.Errorf("Comment() = %v", err)
+	}
+	if err := s.Event("", func() {}); err == nil {
+		t.Error("Event(func) = nil, want error")
+	}
+	want := "data: {\"a\":1}\n\n" +
+		"event: up date\ndata: \"x\\ny\"\n\n" +
+		": ping\n: pong\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+type unflushableWriter struct{ http.ResponseWriter }
+
+func TestSSEStreamUnflushable(t *testing.T) {
+	if _, err := NewSSEStream(unflushableWriter{httptest.NewRecorder()}); err == nil {
+		t.Error("NewSSEStream(unflushable) = nil error, want error")
+	}
+}
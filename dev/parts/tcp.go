@@ -0,0 +1,259 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+var (
+	tcpListenerPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "manager",
+			Direction: pin.Input,
+			Type:      "parts.TCPListenerManager",
+		},
+		&pin.Definition{
+			Name:      "conns",
+			Direction: pin.Output,
+			Type:      "*parts.Conn",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+
+	tcpDialerPins = pin.NewMap(
+		&pin.Definition{
+			Name:      "addresses",
+			Direction: pin.Input,
+			Type:      "string",
+		},
+		&pin.Definition{
+			Name:      "conns",
+			Direction: pin.Output,
+			Type:      "*parts.Conn",
+		},
+		&pin.Definition{
+			Name:      "errors",
+			Direction: pin.Output,
+			Type:      "error",
+		},
+	)
+)
+
+func init() {
+	model.RegisterPartType("TCPListener", "Network", &model.PartType{
+		New: func() model.Part { return &TCPListener{} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Listener",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="tcplistener-network">Network</label>
+					<select id="tcplistener-network" name="tcplistener-network">
+						<option value="tcp" selected>TCP</option>
+						<option value="unix">Unix domain socket</option>
+					</select>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A TCPListener part accepts network connections, and sends each one
+				to conns as a <code>*parts.Conn</code>. A ConnLines part can turn
+				the connections into channels of messages.
+			</p><p>
+				For each TCPListenerManager received, a new listener is started on the
+				address given by the manager, for example <code>:8080</code>, or
+				the path of the socket file for a Unix domain socket. (An empty TCP
+				address listens on any free port.) Managers can be made with
+				<code>parts.NewTCPListenerManager</code>, or, to use an existing
+				listener, <code>parts.NewHTTPServerListenerManager</code>.
+			</p><p>
+				The listener runs until the manager's Shutdown is called. It then
+				stops accepting connections, and waits for the connections it
+				accepted to be closed, until the shutdown context is done, at which
+				point it closes them.
+			</p><p>
+				Multiplicity limits how many listeners may be run at once.
+			</p>
+			</div>`,
+			},
+		},
+	})
+
+	model.RegisterPartType("TCPDialer", "Network", &model.PartType{
+		New: func() model.Part { return &TCPDialer{Timeout: 30 * time.Second} },
+		Panels: []model.PartPanel{
+			{
+				Name: "Dialer",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="tcpdialer-network">Network</label>
+					<select id="tcpdialer-network" name="tcpdialer-network">
+						<option value="tcp" selected>TCP</option>
+						<option value="unix">Unix domain socket</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="tcpdialer-timeout">Timeout</label>
+					<input id="tcpdialer-timeout" name="tcpdialer-timeout" type="text" required title="Must be a parseable time.Duration" value="30s"></input>
+				</div>
+				<div class="formfield">
+					<label for="tcpdialer-keepalive">Keep-alive period</label>
+					<input id="tcpdialer-keepalive" name="tcpdialer-keepalive" type="text" required title="Must be a parseable time.Duration" value="0s"></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A TCPDialer part makes a network connection to each address
+				received, for example <code>example.com:7</code> (or the path of
+				the socket file for a Unix domain socket), and sends it to conns as
+				a <code>*parts.Conn</code>. A ConnLines part can turn the
+				connections into channels of messages.
+			</p><p>
+				If the connection can't be made, the error is sent to errors, if
+				it is connected. A timeout of zero means no timeout (other than the
+				operating system's), and a keep-alive period of zero means the
+				default for TCP.
+			</p><p>
+				Multiplicity limits the number of connections being made at once.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// TCPListener is a part which listens for network connections.
+type TCPListener struct {
+	Network string `json:"network,omitempty"`
+}
+
+// Clone returns a clone of this TCPListener.
+func (l *TCPListener) Clone() model.Part {
+	l0 := *l
+	return &l0
+}
+
+// Impl returns the TCPListener implementation.
+func (l *TCPListener) Impl(*model.Node) model.PartImpl {
+	checkTCPNetwork(l.Network)
+	return model.PartImpl{
+		Imports: []string{`"github.com/google/shenzhen-go/dev/parts"`},
+		Body: fmt.Sprintf(`
+	for mgr := range manager {
+		srv := &parts.ConnServer{Conns: conns}
+		done := make(chan struct{})
+		go func() {
+			l, err := parts.TCPListenerListen(mgr, %q)
+			if err == nil {
+				err = srv.Serve(l)
+			}
+			if err != nil && errors != nil {
+				errors <- err
+			}
+			close(done)
+		}()
+		if err := srv.Shutdown(mgr.Wait()); err != nil && errors != nil {
+			errors <- err
+		}
+		<-done
+	}`, l.Network),
+		Tail: `close(conns)
+		if errors != nil {
+			close(errors)
+		}`,
+	}
+}
+
+// Pins returns a map declaring a manager input and conns and errors outputs.
+func (l *TCPListener) Pins() pin.Map { return tcpListenerPins }
+
+// TypeKey returns "TCPListener".
+func (l *TCPListener) TypeKey() string { return "TCPListener" }
+
+// TCPDialer is a part which makes network connections.
+type TCPDialer struct {
+	Network   string        `json:"network,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	KeepAlive time.Duration `json:"keep_alive,omitempty"`
+}
+
+// Clone returns a clone of this TCPDialer.
+func (d *TCPDialer) Clone() model.Part {
+	d0 := *d
+	return &d0
+}
+
+// Impl returns the TCPDialer implementation.
+func (d *TCPDialer) Impl(*model.Node) model.PartImpl {
+	checkTCPNetwork(d.Network)
+	network := d.Network
+	if network == "" {
+		network = "tcp"
+	}
+	return model.PartImpl{
+		Imports: []string{
+			`"net"`,
+			`"github.com/google/shenzhen-go/dev/parts"`,
+		},
+		Body: fmt.Sprintf(`
+	dialer := &net.Dialer{
+		Timeout:   %d, // %v
+		KeepAlive: %d, // %v
+	}
+	for addr := range addresses {
+		c, err := dialer.Dial(%q, addr)
+		if err != nil {
+			if errors != nil {
+				errors <- err
+			}
+			continue
+		}
+		conns <- parts.NewConn(c)
+	}`, d.Timeout, d.Timeout, d.KeepAlive, d.KeepAlive, network),
+		Tail: `close(conns)
+		if errors != nil {
+			close(errors)
+		}`,
+	}
+}
+
+// Pins returns a map declaring an addresses input and conns and errors outputs.
+func (d *TCPDialer) Pins() pin.Map { return tcpDialerPins }
+
+// TypeKey returns "TCPDialer".
+func (d *TCPDialer) TypeKey() string { return "TCPDialer" }
+
+func checkTCPNetwork(network string) {
+	switch network {
+	case "", "tcp", "unix":
+	default:
+		panic("unknown network " + network)
+	}
+}
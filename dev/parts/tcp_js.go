@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	selectTCPListenerNetwork = doc.ElementByID("tcplistener-network")
+
+	selectTCPDialerNetwork  = doc.ElementByID("tcpdialer-network")
+	inputTCPDialerTimeout   = doc.ElementByID("tcpdialer-timeout")
+	inputTCPDialerKeepAlive = doc.ElementByID("tcpdialer-keepalive")
+
+	focusedTCPListener *TCPListener
+	focusedTCPDialer   *TCPDialer
+)
+
+func init() {
+	selectTCPListenerNetwork.AddEventListener("change", func(dom.Object) {
+		focusedTCPListener.Network = selectTCPListenerNetwork.Get("value").String()
+	})
+
+	selectTCPDialerNetwork.AddEventListener("change", func(dom.Object) {
+		focusedTCPDialer.Network = selectTCPDialerNetwork.Get("value").String()
+	})
+	inputTCPDialerTimeout.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedTCPDialer.Timeout = t
+	}))
+	inputTCPDialerKeepAlive.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedTCPDialer.KeepAlive = t
+	}))
+}
+
+func (l *TCPListener) GainFocus() {
+	focusedTCPListener = l
+	selectTCPListenerNetwork.Set("value", l.Network)
+}
+
+func (d *TCPDialer) GainFocus() {
+	focusedTCPDialer = d
+	selectTCPDialerNetwork.Set("value", d.Network)
+	inputTCPDialerTimeout.Set("value", d.Timeout.String())
+	inputTCPDialerKeepAlive.Set("value", d.KeepAlive.String())
+}
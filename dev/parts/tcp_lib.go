@@ -0,0 +1,279 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Conn is a network connection accepted by a TCPListener part or made by a
+// TCPDialer part. It can be closed more than once, and Done reports when it
+// has been closed, so that a TCPListener can wait for its connections to
+// finish when shutting down.
+type Conn struct {
+	net.Conn
+
+	once sync.Once
+	err  error
+	done chan struct{}
+}
+
+// NewConn wraps a net.Conn.
+func NewConn(c net.Conn) *Conn {
+	return &Conn{
+		Conn: c,
+		done: make(chan struct{}),
+	}
+}
+
+// Close closes the connection. Only the first call closes the underlying
+// connection; later calls return the same result.
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		// Close done first, so that anything reading from the connection
+		// can tell that the error it gets is from closing.
+		close(c.done)
+		c.err = c.Conn.Close()
+	})
+	return c.err
+}
+
+// Done returns a channel that is closed when Close is called.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// TCPListenerManager is information required to start and stop one listener
+// with the TCPListener part. It has the same methods as HTTPServerManager,
+// and similarly, a manager can supply its own listener by also having a
+// Listener method (as managers from NewHTTPServerListenerManager do).
+type TCPListenerManager interface {
+	// Addr is the listen address.
+	Addr() string
+
+	// Shutdown passes a shutdown context to the listener. Once it is called,
+	// no more connections are accepted, and when the context is done any
+	// connections still open are closed.
+	Shutdown(context.Context)
+
+	// Wait waits until Shutdown is called, and then returns the context it was called with.
+	Wait() context.Context
+}
+
+// NewTCPListenerManager creates a channel-based TCPListenerManager.
+func NewTCPListenerManager(addr string) TCPListenerManager {
+	return NewHTTPServerManager(addr)
+}
+
+// TCPListenerListen returns a listener for a TCPListener part, in the same
+// way as HTTPServerListen, except that an empty TCP address means any port.
+func TCPListenerListen(mgr TCPListenerManager, network string) (net.Listener, error) {
+	return managerListen(mgr, network, "")
+}
+
+// ConnServer accepts connections from a listener, and sends them on Conns.
+// Like http.Server, it keeps track of the connections so that it can be
+// shut down gracefully.
+type ConnServer struct {
+	Conns chan<- *Conn
+
+	mu       sync.Mutex
+	listener net.Listener
+	open     map[*Conn]struct{}
+	closing  bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Serve accepts connections from l until the server is shut down, or there
+// is an error other than a temporary one. It closes l when it returns.
+// After Shutdown, it returns nil.
+func (s *ConnServer) Serve(l net.Listener) error {
+	defer l.Close()
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.init()
+	s.listener = l
+	s.mu.Unlock()
+
+	var delay time.Duration
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				// Back off, as http.Server does.
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		c := NewConn(nc)
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			c.Close()
+			return nil
+		}
+		s.open[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			<-c.Done()
+			s.mu.Lock()
+			delete(s.open, c)
+			s.mu.Unlock()
+			s.wg.Done()
+		}()
+		select {
+		case s.Conns <- c:
+		case <-s.quit:
+			c.Close()
+			return nil
+		}
+	}
+}
+
+// Shutdown stops the server accepting connections, and waits for all the
+// connections it accepted to be closed. If ctx is done first, Shutdown
+// closes the remaining connections and returns the context's error.
+// Shutdown can be called before Serve, in which case Serve returns
+// immediately.
+func (s *ConnServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.init()
+	if !s.closing {
+		s.closing = true
+		close(s.quit)
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	for c := range s.open {
+		c.Close()
+	}
+	s.mu.Unlock()
+	<-done
+	return ctx.Err()
+}
+
+// init initialises s. s.mu must be held.
+func (s *ConnServer) init() {
+	if s.open == nil {
+		s.open = make(map[*Conn]struct{})
+		s.quit = make(chan struct{})
+	}
+}
+
+func (s *ConnServer) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// ConnMessages is a connection split into messages by a ConnLines part.
+type ConnMessages struct {
+	Conn *Conn
+
+	// In receives the messages read from the connection. It is closed when
+	// there are no more, at the end of the input or after a read error.
+	In <-chan string
+
+	// Out sends messages to be written to the connection. Closing Out
+	// closes the connection, after writing the messages already sent.
+	Out chan<- string
+}
+
+// ConnFraming is how a ConnLines part splits a connection into messages.
+type ConnFraming string
+
+// Values for ConnFraming.
+const (
+	// ConnFramingLines uses lines ending in "\n" (or "\r\n").
+	ConnFramingLines ConnFraming = "lines"
+
+	// ConnFramingFrames uses frames of any bytes, each preceded by its length
+	// as a 4-byte, big-endian, unsigned integer.
+	ConnFramingFrames ConnFraming = "frames"
+)
+
+// ErrFrameTooLong is returned when writing a frame longer than the
+// length prefix can describe.
+var ErrFrameTooLong = errors.New("frame too long")
+
+// ScanFrames is a bufio.SplitFunc for length-prefixed frames, as written by
+// WriteFrame. The frame size is limited by the scanner's maximum buffer size.
+func ScanFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) < 4 {
+		if atEOF && len(data) > 0 {
+			return 0, nil, fmt.Errorf("truncated frame length: %d bytes", len(data))
+		}
+		return 0, nil, nil
+	}
+	n := int(binary.BigEndian.Uint32(data))
+	if len(data) < 4+n {
+		if atEOF {
+			return 0, nil, fmt.Errorf("truncated frame: %d of %d bytes", len(data)-4, n)
+		}
+		return 0, nil, nil
+	}
+	return 4 + n, data[4 : 4+n], nil
+}
+
+const maxFrameSize = 1<<32 - 1
+
+// WriteFrame writes msg to w, preceded by its length, so that it can be read
+// with ScanFrames.
+func WriteFrame(w *bufio.Writer, msg string) error {
+	if uint64(len(msg)) > maxFrameSize {
+		return ErrFrameTooLong
+	}
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(msg)))
+	if _, err := w.Write(n[:]); err != nil {
+		return err
+	}
+	_, err := w.WriteString(msg)
+	return err
+}
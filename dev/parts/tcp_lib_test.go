@@ -0,0 +1,178 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConnClose(t *testing.T) {
+	a, b := net.Pipe()
+	defer b.Close()
+	c := NewConn(a)
+	select {
+	case <-c.Done():
+		t.Fatal("Done closed before Close")
+	default:
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("first Close() = %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done not closed after Close")
+	}
+}
+
+func TestConnServerShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	conns := make(chan *Conn)
+	srv := &ConnServer{Conns: conns}
+	served := make(chan error)
+	go func() { served <- srv.Serve(l) }()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	defer client.Close()
+	c := <-conns
+
+	// Shutdown waits for c until the context is done, then closes it.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if got, want := srv.Shutdown(ctx), context.DeadlineExceeded; got != want {
+		t.Errorf("Shutdown() = %v, want %v", got, want)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("connection not closed by Shutdown")
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v, want nil", err)
+	}
+	if _, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Error("net.Dial() after Shutdown = nil error, want error")
+	}
+}
+
+func TestConnServerShutdownGraceful(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	conns := make(chan *Conn)
+	srv := &ConnServer{Conns: conns}
+	served := make(chan error)
+	go func() { served <- srv.Serve(l) }()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	defer client.Close()
+	c := <-conns
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Close()
+	}()
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %v, want nil", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v, want nil", err)
+	}
+}
+
+func TestConnServerShutdownBeforeServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	srv := &ConnServer{Conns: make(chan *Conn)}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %v, want nil", err)
+	}
+	if err := srv.Serve(l); err != nil {
+		t.Errorf("Serve() = %v, want nil", err)
+	}
+	if _, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Error("net.Dial() after Serve = nil error, want error")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	msgs := []string{"hello", "", "multi\nline\x00bytes"}
+	buf := bytes.NewBuffer(nil)
+	w := bufio.NewWriter(buf)
+	for _, m := range msgs {
+		if err := WriteFrame(w, m); err != nil {
+			t.Fatalf("WriteFrame(%q) = %v", m, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	sc.Split(ScanFrames)
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("scanner error = %v", err)
+	}
+	if !reflect.DeepEqual(got, msgs) {
+		t.Errorf("scanned frames = %q, want %q", got, msgs)
+	}
+}
+
+func TestScanFramesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		max   int
+	}{
+		{"truncated length", []byte{0, 0}, 1024},
+		{"truncated frame", []byte{0, 0, 0, 5, 'a', 'b'}, 1024},
+		{"too long", []byte{0, 0, 4, 0, 'a', 'b', 'c', 'd'}, 16},
+	}
+	for _, test := range tests {
+		sc := bufio.NewScanner(bytes.NewReader(test.input))
+		sc.Buffer(nil, test.max)
+		sc.Split(ScanFrames)
+		for sc.Scan() {
+			t.Errorf("%s: scanned frame %q, want none", test.name, sc.Text())
+		}
+		if sc.Err() == nil {
+			t.Errorf("%s: scanner error = nil, want error", test.name)
+		}
+	}
+}
@@ -15,13 +15,85 @@
 package parts
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"text/template"
 
 	"github.com/google/shenzhen-go/dev/model"
 	"github.com/google/shenzhen-go/dev/model/pin"
 )
 
+var (
+	transformOrderedHeadTmpl = template.Must(template.New("transform-ordered-head").Parse(`
+	type transformInput struct {
+		seq   uint64
+		input {{.InType}}
+	}
+	type transformResult struct {
+		seq     uint64
+		outputs []{{.OutType}}
+	}
+	{{if .BufferSize -}}
+	const reorderBufferSize = {{.BufferSize}}
+	{{- else -}}
+	reorderBufferSize := multiplicity
+	{{- end}}
+	window := make(chan struct{}, reorderBufferSize)
+	transformInputs := make(chan transformInput, multiplicity)
+	transformResults := make(chan transformResult, multiplicity)
+
+	// Sequencer: number each input.
+	go func() {
+		seq := uint64(0)
+		for input := range inputs {
+			window <- struct{}{}
+			transformInputs <- transformInput{seq: seq, input: input}
+			seq++
+		}
+		close(transformInputs)
+	}()
+
+	// Reorderer: send outputs in input order.
+	reorderDone := make(chan struct{})
+	go func() {
+		defer close(reorderDone)
+		pending := make(map[uint64][]{{.OutType}}, reorderBufferSize)
+		next := uint64(0)
+		for r := range transformResults {
+			pending[r.seq] = r.outputs
+			for {
+				outs, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				if outputs != nil {
+					for _, out := range outs {
+						outputs <- out
+					}
+				}
+				next++
+				<-window
+			}
+		}
+	}()`))
+
+	transformOrderedBodyTmpl = template.Must(template.New("transform-ordered-body").Parse(`
+	for in := range transformInputs {
+		outs := make(chan {{.OutType}})
+		go func(input {{.InType}}, outputs chan<- {{.OutType}}) {
+			defer close(outputs)
+			{{.Body}}
+		}(in.input, outs)
+		var collected []{{.OutType}}
+		for out := range outs {
+			collected = append(collected, out)
+		}
+		transformResults <- transformResult{seq: in.seq, outputs: collected}
+	}`))
+)
+
 func init() {
 	model.RegisterPartType("Transform", "General", &model.PartType{
 		New: func() model.Part {
@@ -44,6 +116,19 @@ func init() {
 					</div>
 				</div>`,
 			},
+			{
+				Name: "Order",
+				Editor: `<div class="form">
+					<div class="formfield">
+						<input id="transform-ordered" name="transform-ordered" type="checkbox"></input>
+						<label for="transform-ordered">Preserve input order</label>
+					</div>
+					<div class="formfield">
+						<label for="transform-reorderbuffersize">Reorder buffer size</label>
+						<input id="transform-reorderbuffersize" name="transform-reorderbuffersize" type="number" required title="Must be a whole number. 0 means the same as the multiplicity." value="0"></input>
+					</div>
+				</div>`,
+			},
 			{
 				Name:   "Imports",
 				Editor: `<div class="codeedit" id="transform-imports"></div>`,
@@ -64,6 +149,15 @@ func init() {
 				The BYO code body can be any function body but must transform or filter the
 				input value (available as a value called <code>input</code>) and write any output
 				to <code>outputs</code>.
+			</p><p>
+				With a multiplicity greater than 1, inputs are transformed concurrently,
+				and outputs are normally sent in whatever order they are produced.
+				If "Preserve input order" is enabled, the outputs for each input are
+				held back until the outputs for all earlier inputs have been sent.
+				The reorder buffer size limits how many inputs may be in progress or
+				waiting to be sent at once; when it is full, no more inputs are read
+				until the earliest one is sent. A size of 0 means the same as the
+				multiplicity.
 			</p>
 			</div>`,
 			},
@@ -71,21 +165,32 @@ func init() {
 	})
 }
 
-// Transform is a part which immediately closes the output channel.
+// Transform is a part which converts inputs into outputs using BYO code.
 type Transform struct {
 	Imports    []string `json:"imports"`
 	Body       []string `json:"body"`
 	InputType  string   `json:"input_type"`
 	OutputType string   `json:"output_type"`
+
+	// Ordered causes outputs to be sent in input order when
+	// multiplicity is greater than 1.
+	Ordered           bool `json:"ordered,omitempty"`
+	ReorderBufferSize uint `json:"reorder_buffer_size,omitempty"`
 }
 
 // Clone returns a clone of this Transform.
 func (t *Transform) Clone() model.Part {
-	return &Transform{Body: t.Body}
+	t0 := *t
+	t0.Imports = append([]string(nil), t.Imports...)
+	t0.Body = append([]string(nil), t.Body...)
+	return &t0
 }
 
 // Impl returns the Transform implementation.
 func (t *Transform) Impl(n *model.Node) model.PartImpl {
+	if t.Ordered && n.Multiplicity != "1" {
+		return t.orderedImpl(n)
+	}
 	return model.PartImpl{
 		Imports: t.Imports,
 		Body: fmt.Sprintf(`for input := range inputs {
@@ -97,6 +202,38 @@ func (t *Transform) Impl(n *model.Node) model.PartImpl {
 	}
 }
 
+// orderedImpl tags each input with a sequence number, transforms inputs
+// concurrently in the body instances, and sends outputs in sequence order.
+// The window channel bounds the number of inputs between the sequencer and
+// the reorderer, and hence the size of the reorder buffer.
+func (t *Transform) orderedImpl(n *model.Node) model.PartImpl {
+	params := struct {
+		InType, OutType string
+		BufferSize      uint
+		Body            string
+	}{
+		InType:     n.PinTypes["inputs"].String(),
+		OutType:    n.PinTypes["outputs"].String(),
+		BufferSize: t.ReorderBufferSize,
+		Body:       strings.Join(t.Body, "\n"),
+	}
+	h, b := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	if err := transformOrderedHeadTmpl.Execute(h, params); err != nil {
+		panic("couldn't execute transform-ordered-head template: " + err.Error())
+	}
+	if err := transformOrderedBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute transform-ordered-body template: " + err.Error())
+	}
+	return model.PartImpl{
+		Imports: t.Imports,
+		Head:    h.String(),
+		Body:    b.String(),
+		Tail: `close(transformResults)
+		<-reorderDone
+		if outputs != nil { close(outputs) }`,
+	}
+}
+
 // Pins returns a map declaring a single input and single output of any type.
 func (t *Transform) Pins() pin.Map {
 	return pin.NewMap(
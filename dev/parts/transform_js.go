@@ -27,6 +27,8 @@ var (
 
 	inputTransformInputType  = doc.ElementByID("transform-inputtype")
 	inputTransformOutputType = doc.ElementByID("transform-outputtype")
+	inputTransformOrdered    = doc.ElementByID("transform-ordered")
+	inputTransformBufferSize = doc.ElementByID("transform-reorderbuffersize")
 	linkTransformFormat      = doc.ElementByID("transform-format-link")
 
 	focusedTransform *Transform
@@ -43,6 +45,12 @@ func init() {
 	inputTransformOutputType.AddEventListener("change", func(dom.Object) {
 		focusedTransform.OutputType = inputTransformOutputType.Get("value").String()
 	})
+	inputTransformOrdered.AddEventListener("change", func(dom.Object) {
+		focusedTransform.Ordered = inputTransformOrdered.Get("checked").Bool()
+	})
+	inputTransformBufferSize.AddEventListener("change", func(dom.Object) {
+		focusedTransform.ReorderBufferSize = uint(inputTransformBufferSize.Get("value").Int())
+	})
 	linkTransformFormat.AddEventListener("click", formatHandler(transformBodySession))
 }
 
@@ -58,6 +66,8 @@ func (t *Transform) GainFocus() {
 	focusedTransform = t
 	inputTransformInputType.Set("value", t.InputType)
 	inputTransformOutputType.Set("value", t.OutputType)
+	inputTransformOrdered.Set("checked", t.Ordered)
+	inputTransformBufferSize.Set("value", t.ReorderBufferSize)
 	transformImportsSession.SetValue(strings.Join(t.Imports, "\n"))
 	transformBodySession.SetValue(strings.Join(t.Body, "\n"))
 }
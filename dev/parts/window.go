@@ -0,0 +1,435 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/model"
+	"github.com/google/shenzhen-go/dev/model/pin"
+)
+
+const windowTypeParam = "$T"
+
+func windowOutputType(rt string) string {
+	return fmt.Sprintf("struct{ Start time.Time; End time.Time; Result %s }", rt)
+}
+
+var windowBodyTmpl = template.Must(template.New("window-body").Parse(`
+	newAcc := func() {{.AccType}} {
+		{{if .Init -}}
+		return {{.Init}}
+		{{- else -}}
+		var acc {{.AccType}}
+		return acc
+		{{- end}}
+	}
+	reduce := func(acc {{.AccType}}, in {{.Type}}) {{.AccType}} {
+		{{.Reduce}}
+		return acc
+	}
+	type window struct {
+		start, end time.Time
+		{{if not .TimeWindow}}n int{{end}}
+		acc        {{.AccType}}
+	}
+	emit := func(w *window) {
+		output <- {{.OutputType}}{
+			Start:  w.start,
+			End:    w.end,
+			Result: w.acc,
+		}
+	}
+	{{if .TimeWindow -}}
+	const (
+		size  = {{.SizeNanos}} // {{.Size}}
+		slide = {{.SlideNanos}} // {{.Slide}}
+	)
+	windows := make(map[time.Time]*window)
+	var watermark time.Time
+
+	// flush sends, in order, all windows that end at or before the watermark.
+	flush := func() {
+		var done []*window
+		for s, w := range windows {
+			if !w.end.After(watermark) {
+				done = append(done, w)
+				delete(windows, s)
+			}
+		}
+		sort.Slice(done, func(i, j int) bool { return done[i].start.Before(done[j].start) })
+		for _, w := range done {
+			emit(w)
+		}
+	}
+	{{if not .EventTime -}}
+	ticker := time.NewTicker(slide)
+	defer ticker.Stop()
+	{{end -}}
+windowLoop:
+	for {
+		select {
+		case in, open := <-input:
+			if !open {
+				break windowLoop
+			}
+			{{if .EventTime -}}
+			t := {{.EventTime}}
+			{{- else -}}
+			t := time.Now()
+			{{- end}}
+			// Add to every window containing t, except windows already sent.
+			for s := t.Truncate(slide); s.Add(size).After(t); s = s.Add(-slide) {
+				if !s.Add(size).After(watermark) {
+					break
+				}
+				w := windows[s]
+				if w == nil {
+					w = &window{start: s, end: s.Add(size), acc: newAcc()}
+					windows[s] = w
+				}
+				w.acc = reduce(w.acc, in)
+			}
+			{{if .EventTime -}}
+			if t.After(watermark) {
+				watermark = t
+				flush()
+			}
+			{{- end}}
+		{{if not .EventTime -}}
+		case now := <-ticker.C:
+			watermark = now
+			flush()
+		{{end -}}
+		}
+	}
+	// Send remaining windows, even though they aren't finished.
+	watermark = time.Unix(1<<62, 0)
+	flush()
+	{{- else -}}
+	const (
+		count = {{.Count}}
+		step  = {{.CountSlide}}
+	)
+	var (
+		windows []*window // oldest first
+		seen    int
+	)
+	for in := range input {
+		{{if .EventTime -}}
+		t := {{.EventTime}}
+		{{- else -}}
+		t := time.Now()
+		{{- end}}
+		if seen%step == 0 {
+			windows = append(windows, &window{start: t, acc: newAcc()})
+		}
+		seen++
+		for _, w := range windows {
+			w.acc = reduce(w.acc, in)
+			w.end = t
+			w.n++
+		}
+		if len(windows) > 0 && windows[0].n == count {
+			emit(windows[0])
+			windows = windows[1:]
+		}
+	}
+	// Send remaining windows, even though they aren't full.
+	for _, w := range windows {
+		emit(w)
+	}
+	{{- end}}`))
+
+func init() {
+	model.RegisterPartType("Window", "Flow", &model.PartType{
+		New: func() model.Part {
+			return &Window{
+				Kind:    WindowKindTime,
+				Size:    time.Minute,
+				Count:   100,
+				Reducer: WindowReduceCount,
+				AccType: "$Acc",
+			}
+		},
+		Panels: []model.PartPanel{
+			{
+				Name: "Window",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="window-kind">Window kind</label>
+					<select id="window-kind" name="window-kind">
+						<option value="time" selected>Time</option>
+						<option value="count">Count</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="window-size">Time window size</label>
+					<input id="window-size" name="window-size" type="text" required title="Must be a parseable time.Duration. 0s means the default, 1m." value="1m0s"></input>
+				</div>
+				<div class="formfield">
+					<label for="window-slide">Time window slide</label>
+					<input id="window-slide" name="window-slide" type="text" required title="Must be a parseable time.Duration. 0s means tumbling windows." value="0s"></input>
+				</div>
+				<div class="formfield">
+					<label for="window-count">Count window size</label>
+					<input id="window-count" name="window-count" type="number" required title="Must be a whole number, at least 1." value="100"></input>
+				</div>
+				<div class="formfield">
+					<label for="window-countslide">Count window slide</label>
+					<input id="window-countslide" name="window-countslide" type="number" required min="0" title="Must be a whole number, no more than the count. 0 means tumbling windows." value="0"></input>
+				</div>
+				<div class="formfield">
+					<label for="window-eventtime">Event time expression</label>
+					<input id="window-eventtime" name="window-eventtime" type="text" title="A Go expression of type time.Time using in, or empty for arrival time" value=""></input>
+				</div>
+			</div>`,
+			},
+			{
+				Name: "Reducer",
+				Editor: `<div class="form">
+				<div class="formfield">
+					<label for="window-reducer">Reducer</label>
+					<select id="window-reducer" name="window-reducer">
+						<option value="count" selected>Count</option>
+						<option value="sum">Sum</option>
+						<option value="collect">Collect</option>
+						<option value="custom">Custom</option>
+					</select>
+				</div>
+				<div class="formfield">
+					<label for="window-acctype">Custom result type</label>
+					<input id="window-acctype" name="window-acctype" type="text" value="$Acc"></input>
+				</div>
+				<div class="formfield">
+					<label for="window-init">Custom initial value</label>
+					<input id="window-init" name="window-init" type="text" title="A Go expression, or empty for the zero value" value=""></input>
+				</div>
+				</div>
+				<div class="formfield">
+					<span class="link" id="window-format-link">Format</span>
+				</div>
+				<div class="codeedit formfield" id="window-reduce"></div>`,
+			},
+			{
+				Name:   "Imports",
+				Editor: `<div class="codeedit" id="window-imports"></div>`,
+			},
+			{
+				Name: "Help",
+				Editor: `<div>
+			<p>
+				A Window part aggregates input values over windows, and sends one
+				result per window. Each result is a struct with fields Start, End,
+				and Result.
+			</p><p>
+				Time windows have a fixed duration (one minute, if the size is 0s). Tumbling time windows (slide 0s)
+				do not overlap; sliding time windows start every slide duration, so
+				each value can belong to more than one window. Windows are aligned to
+				multiples of the slide (or size) since the zero time. A time window is
+				sent once it has ended. Values that would only belong to windows that
+				have already been sent are ignored.
+			</p><p>
+				Count windows contain a fixed number of values. Tumbling count windows
+				(slide 0) do not overlap; sliding count windows start every slide values.
+				A slide larger than the count is treated as tumbling, so no values
+				are skipped.
+				Start and End are the times of the first and last value in the window.
+			</p><p>
+				By default, the time of each value is the time it arrived. Alternatively,
+				an event time expression (a Go expression of type <code>time.Time</code>,
+				in terms of the input value <code>in</code>, for example
+				<code>in.Timestamp</code>) can be provided. With event time, time
+				windows are sent when a value arrives with a later event time than
+				the end of the window.
+			</p><p>
+				The reducer determines the result of each window: Count (an int),
+				Sum (the sum of the values, which must be numeric), Collect (a slice
+				of the values), or Custom. Custom reducers start with the initial
+				value (or the zero value) in <code>acc</code>, and run the reducer
+				code for each value <code>in</code> to update <code>acc</code>.
+			</p><p>
+				When the input is closed, any unfinished windows are sent, and then
+				the output is closed.
+				Each instance aggregates independently.
+			</p>
+			</div>`,
+			},
+		},
+	})
+}
+
+// WindowKind describes how a Window part delimits windows.
+type WindowKind string
+
+// Valid values of WindowKind.
+const (
+	WindowKindTime  WindowKind = "time"
+	WindowKindCount WindowKind = "count"
+)
+
+// WindowReducer describes how a Window part combines values in a window.
+type WindowReducer string
+
+// Valid values of WindowReducer.
+const (
+	WindowReduceCount   WindowReducer = "count"
+	WindowReduceSum     WindowReducer = "sum"
+	WindowReduceCollect WindowReducer = "collect"
+	WindowReduceCustom  WindowReducer = "custom"
+)
+
+// Window is a part which aggregates values over windows of time or count.
+type Window struct {
+	Kind       WindowKind    `json:"kind"`
+	Size       time.Duration `json:"size,omitempty"`
+	Slide      time.Duration `json:"slide,omitempty"`
+	Count      uint          `json:"count,omitempty"`
+	CountSlide uint          `json:"count_slide,omitempty"`
+	EventTime  string        `json:"event_time,omitempty"`
+
+	Reducer WindowReducer `json:"reducer"`
+
+	// Used by the custom reducer.
+	Imports []string `json:"imports,omitempty"`
+	AccType string   `json:"acc_type,omitempty"`
+	Init    string   `json:"init,omitempty"`
+	Reduce  []string `json:"reduce,omitempty"`
+}
+
+// Clone returns a clone of this Window.
+func (w *Window) Clone() model.Part {
+	w0 := *w
+	w0.Imports = append([]string(nil), w.Imports...)
+	w0.Reduce = append([]string(nil), w.Reduce...)
+	return &w0
+}
+
+// resultType returns the type of the Result field, in terms of the type
+// parameters of the part.
+func (w *Window) resultType() string {
+	switch w.Reducer {
+	case WindowReduceCount:
+		return "int"
+	case WindowReduceSum:
+		return windowTypeParam
+	case WindowReduceCollect:
+		return "[]" + windowTypeParam
+	case WindowReduceCustom:
+		if w.AccType == "" {
+			return "$Acc"
+		}
+		return w.AccType
+	default:
+		panic("unknown reducer " + w.Reducer)
+	}
+}
+
+// Impl returns the Window implementation.
+func (w *Window) Impl(n *model.Node) model.PartImpl {
+	params := struct {
+		Type, AccType, OutputType string
+		Init, Reduce, EventTime   string
+		TimeWindow                bool
+		Size, Slide               time.Duration
+		SizeNanos, SlideNanos     int64
+		Count, CountSlide         uint
+	}{
+		Type:       n.TypeParams[windowTypeParam].String(),
+		OutputType: n.PinTypes["output"].String(),
+		EventTime:  w.EventTime,
+		Size:       w.Size,
+		Slide:      w.Slide,
+		Count:      w.Count,
+		CountSlide: w.CountSlide,
+	}
+	switch w.Reducer {
+	case WindowReduceCount:
+		params.AccType = "int"
+		params.Reduce = "acc++"
+	case WindowReduceSum:
+		params.AccType = params.Type
+		params.Reduce = "acc += in"
+	case WindowReduceCollect:
+		params.AccType = "[]" + params.Type
+		params.Reduce = "acc = append(acc, in)"
+	case WindowReduceCustom:
+		params.AccType = resolveType(n, w.resultType())
+		params.Init = w.Init
+		params.Reduce = strings.Join(w.Reduce, "\n")
+	default:
+		panic("unknown reducer " + w.Reducer)
+	}
+	switch w.Kind {
+	case WindowKindTime:
+		params.TimeWindow = true
+		if params.Size <= 0 {
+			params.Size = time.Minute
+		}
+		if params.Slide <= 0 {
+			params.Slide = params.Size
+		}
+		params.SizeNanos, params.SlideNanos = int64(params.Size), int64(params.Slide)
+	case WindowKindCount:
+		if params.Count < 1 {
+			params.Count = 1
+		}
+		if params.CountSlide < 1 || params.CountSlide > params.Count {
+			params.CountSlide = params.Count
+		}
+	default:
+		panic("unknown window kind " + w.Kind)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := windowBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute window-body template: " + err.Error())
+	}
+	imps := []string{`"time"`}
+	if params.TimeWindow {
+		imps = append(imps, `"sort"`)
+	}
+	if w.Reducer == WindowReduceCustom {
+		imps = append(imps, w.Imports...)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    b.String(),
+		Tail:    "close(output)",
+	}
+}
+
+// Pins returns a map declaring an input of any type, and an output
+// of window results.
+func (w *Window) Pins() pin.Map {
+	return pin.NewMap(
+		&pin.Definition{
+			Name:      "input",
+			Direction: pin.Input,
+			Type:      windowTypeParam,
+		},
+		&pin.Definition{
+			Name:      "output",
+			Direction: pin.Output,
+			Type:      windowOutputType(w.resultType()),
+		},
+	)
+}
+
+// TypeKey returns "Window".
+func (w *Window) TypeKey() string { return "Window" }
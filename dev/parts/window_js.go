@@ -0,0 +1,98 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build js
+
+package parts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
+
+var (
+	windowImportsSession, windowReduceSession *dom.AceSession
+
+	selectWindowKind       = doc.ElementByID("window-kind")
+	inputWindowSize        = doc.ElementByID("window-size")
+	inputWindowSlide       = doc.ElementByID("window-slide")
+	inputWindowCount       = doc.ElementByID("window-count")
+	inputWindowCountSlide  = doc.ElementByID("window-countslide")
+	inputWindowEventTime   = doc.ElementByID("window-eventtime")
+	selectWindowReducer    = doc.ElementByID("window-reducer")
+	inputWindowAccType     = doc.ElementByID("window-acctype")
+	inputWindowInit        = doc.ElementByID("window-init")
+	linkWindowReduceFormat = doc.ElementByID("window-format-link")
+
+	focusedWindow *Window
+)
+
+func init() {
+	windowImportsSession = setupAce("window-imports", dom.AceGoMode, windowImportsChange)
+	windowReduceSession = setupAce("window-reduce", dom.AceGoMode, windowReduceChange)
+
+	selectWindowKind.AddEventListener("change", func(dom.Object) {
+		focusedWindow.Kind = WindowKind(selectWindowKind.Get("value").String())
+	})
+	inputWindowSize.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedWindow.Size = t
+	}))
+	inputWindowSlide.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedWindow.Slide = t
+	}))
+	inputWindowCount.AddEventListener("change", func(dom.Object) {
+		focusedWindow.Count = uint(inputWindowCount.Get("value").Int())
+	})
+	inputWindowCountSlide.AddEventListener("change", func(dom.Object) {
+		focusedWindow.CountSlide = uint(inputWindowCountSlide.Get("value").Int())
+	})
+	inputWindowEventTime.AddEventListener("change", func(dom.Object) {
+		focusedWindow.EventTime = inputWindowEventTime.Get("value").String()
+	})
+	selectWindowReducer.AddEventListener("change", func(dom.Object) {
+		focusedWindow.Reducer = WindowReducer(selectWindowReducer.Get("value").String())
+	})
+	inputWindowAccType.AddEventListener("change", func(dom.Object) {
+		focusedWindow.AccType = inputWindowAccType.Get("value").String()
+	})
+	inputWindowInit.AddEventListener("change", func(dom.Object) {
+		focusedWindow.Init = inputWindowInit.Get("value").String()
+	})
+	linkWindowReduceFormat.AddEventListener("click", formatHandler(windowReduceSession))
+}
+
+func windowImportsChange(dom.Object) {
+	focusedWindow.Imports = stripCR(strings.Split(windowImportsSession.Value(), "\n"))
+}
+
+func windowReduceChange(dom.Object) {
+	focusedWindow.Reduce = stripCR(strings.Split(windowReduceSession.Value(), "\n"))
+}
+
+func (w *Window) GainFocus() {
+	focusedWindow = w
+	selectWindowKind.Set("value", w.Kind)
+	inputWindowSize.Set("value", w.Size.String())
+	inputWindowSlide.Set("value", w.Slide.String())
+	inputWindowCount.Set("value", w.Count)
+	inputWindowCountSlide.Set("value", w.CountSlide)
+	inputWindowEventTime.Set("value", w.EventTime)
+	selectWindowReducer.Set("value", w.Reducer)
+	inputWindowAccType.Set("value", w.AccType)
+	inputWindowInit.Set("value", w.Init)
+	windowImportsSession.SetValue(strings.Join(w.Imports, "\n"))
+	windowReduceSession.SetValue(strings.Join(w.Reduce, "\n"))
+}
@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/google/shenzhen-go/dev/model"
 	"github.com/google/shenzhen-go/dev/model/pin"
@@ -31,8 +33,101 @@ type ZipFinishMode string
 const (
 	ZipUntilFirstClose ZipFinishMode = "first"
 	ZipUntilLastClose  ZipFinishMode = "last"
+	ZipCombineLatest   ZipFinishMode = "latest"
 )
 
+// zipSelectBodyTmpl is used for combine-latest mode and for timeouts, which
+// need to wait for all the inputs at once rather than in sequence.
+var zipSelectBodyTmpl = template.Must(template.New("zip-select-body").Parse(`
+	var out {{.OutputType}}
+	{{range .Inputs -}}
+	in{{.}} := input{{.}}
+	got{{.}} := false
+	{{end -}}
+	open := {{len .Inputs}}
+	{{if .Timeout -}}
+	const timeout = {{.TimeoutNanos}} // {{.TimeoutString}}
+	var deadline <-chan time.Time
+	{{end -}}
+	{{if .Latest -}}
+	// ready is true once the tuple should be sent on every change.
+	ready := false
+	check := func() {
+		if !ready {
+			ready = {{range $i, $n := .Inputs}}{{if $i}} && {{end}}got{{$n}}{{end}}
+		}
+		if ready {
+			{{if .Timeout -}}
+			// Every change is sent from now on, so the deadline isn't needed.
+			deadline = nil
+			{{end -}}
+			output <- out
+			return
+		}
+		{{if .Timeout -}}
+		if deadline == nil {
+			deadline = time.After(timeout)
+		}
+		{{- end}}
+	}
+	{{- else -}}
+	{{if .First}}send := true{{end}}
+	// emit sends the current tuple and starts a new one.
+	emit := func() {
+		{{if .First}}if send {
+			output <- out
+		}{{else}}output <- out{{end}}
+		out = {{.OutputType}}{}
+		{{range .Inputs -}}
+		got{{.}}, in{{.}} = false, input{{.}}
+		{{end -}}
+		{{if .Timeout}}deadline = nil{{end}}
+	}
+	check := func() {
+		if !({{range $i, $n := .Inputs}}{{if $i}} || {{end}}got{{$n}}{{end}}) {
+			return
+		}
+		if {{range $i, $n := .Inputs}}{{if $i}} && {{end}}(got{{$n}} || input{{$n}} == nil){{end}} {
+			emit()
+			return
+		}
+		{{if .Timeout -}}
+		if deadline == nil {
+			deadline = time.After(timeout)
+		}
+		{{- end}}
+	}
+	{{- end}}
+	for open > 0 {
+		select {
+		{{range .Inputs -}}
+		case v, ok := <-in{{.}}:
+			if !ok {
+				in{{.}}, input{{.}} = nil, nil
+				open--
+				{{if $.First}}send = false{{end}}
+				{{if not $.Latest}}check(){{end}}
+				continue
+			}
+			out.Field{{.}} = v
+			{{if $.Timeout}}out.Valid{{.}} = true{{end}}
+			got{{.}} = true
+			{{if not $.Latest}}in{{.}} = nil{{end}}
+			check()
+		{{end -}}
+		{{if .Timeout -}}
+		case <-deadline:
+			{{if .Latest -}}
+			deadline = nil
+			ready = true
+			output <- out
+			{{- else -}}
+			emit()
+			{{- end}}
+		{{end -}}
+		}
+	}`))
+
 func init() {
 	model.RegisterPartType("Zip", "Flow", &model.PartType{
 		New: func() model.Part {
@@ -53,7 +148,12 @@ func init() {
 					<select id="zip-finishmode" name="zip-finishmode">
 						<option value="first">Send until first closure</option>
 						<option value="last">Send until last closure</option>
+						<option value="latest">Combine latest</option>
 					</select>
+				</div>
+				<div class="formfield">
+					<label for="zip-timeout">Timeout (0 for none)</label>
+					<input id="zip-timeout" name="zip-timeout" type="text" required title="Must be a parseable time.Duration" value="0s"></input>
 				</div></div>`,
 			},
 			{
@@ -64,6 +164,16 @@ func init() {
 			either to stop sending as soon as any input is closed, or when all inputs
 			are closed.</p><p>
 			Regardless of finish mode, all input values will be consumed.
+			</p><p>
+			In combine latest mode, the part instead sends the latest value from every
+			input whenever any input produces a value, once every input has produced
+			at least one value. It sends until all inputs are closed.
+			</p><p>
+			If the timeout is not zero, the struct also has a boolean field ValidN
+			for each FieldN. If a struct is incomplete for the timeout after its
+			first value arrives, it is sent anyway, with Valid false for the fields
+			that are missing. (In combine latest mode, this means every later value
+			is sent too.)
 			</p>
 			</div>`,
 			},
@@ -75,6 +185,7 @@ func init() {
 type Zip struct {
 	InputNum   uint          `json:"input_num"`
 	FinishMode ZipFinishMode `json:"finish_mode"`
+	Timeout    time.Duration `json:"timeout,omitempty"`
 }
 
 func (z Zip) outputType(types map[string]*source.Type) string {
@@ -85,6 +196,9 @@ func (z Zip) outputType(types map[string]*source.Type) string {
 			tp = types[tp].String()
 		}
 		fs = append(fs, fmt.Sprintf("Field%d %s", i, tp))
+		if z.Timeout > 0 {
+			fs = append(fs, fmt.Sprintf("Valid%d bool", i))
+		}
 
 	}
 	return "struct { " + strings.Join(fs, ";") + " }"
@@ -98,6 +212,9 @@ func (z Zip) Impl(n *model.Node) model.PartImpl {
 	if n.Connections["output"] == "nil" {
 		return model.PartImpl{}
 	}
+	if z.FinishMode == ZipCombineLatest || z.Timeout > 0 {
+		return z.selectImpl(n)
+	}
 
 	bb, wb := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 	bb.WriteString(`for {
@@ -132,6 +249,52 @@ func (z Zip) Impl(n *model.Node) model.PartImpl {
 	}
 }
 
+func (z Zip) selectImpl(n *model.Node) model.PartImpl {
+	params := struct {
+		OutputType             string
+		Inputs                 []uint
+		First, Latest, Timeout bool
+		TimeoutNanos           int64
+		TimeoutString          string
+	}{
+		OutputType:    z.outputType(n.TypeParams),
+		Timeout:       z.Timeout > 0,
+		TimeoutNanos:  int64(z.Timeout),
+		TimeoutString: z.Timeout.String(),
+	}
+	switch z.FinishMode {
+	case ZipUntilFirstClose:
+		params.First = true
+	case ZipUntilLastClose:
+	case ZipCombineLatest:
+		params.Latest = true
+	default:
+		panic("unknown finish mode " + z.FinishMode)
+	}
+	for i := uint(0); i < z.InputNum; i++ {
+		if n.Connections[fmt.Sprintf("input%d", i)] == "nil" {
+			continue
+		}
+		params.Inputs = append(params.Inputs, i)
+	}
+	if len(params.Inputs) == 0 {
+		return model.PartImpl{Tail: "close(output)"}
+	}
+	b := bytes.NewBuffer(nil)
+	if err := zipSelectBodyTmpl.Execute(b, params); err != nil {
+		panic("couldn't execute zip-select-body template: " + err.Error())
+	}
+	var imps []string
+	if params.Timeout {
+		imps = append(imps, `"time"`)
+	}
+	return model.PartImpl{
+		Imports: imps,
+		Body:    b.String(),
+		Tail:    "close(output)",
+	}
+}
+
 // Pins returns a map with N inputs and 1 output.
 func (z Zip) Pins() pin.Map {
 	m := pin.NewMap(&pin.Definition{
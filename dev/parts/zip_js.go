@@ -16,11 +16,16 @@
 
 package parts
 
-import "github.com/google/shenzhen-go/dev/dom"
+import (
+	"time"
+
+	"github.com/google/shenzhen-go/dev/dom"
+)
 
 var (
 	inputZipInputNum    = doc.ElementByID("zip-inputnum")
 	selectZipFinishMode = doc.ElementByID("zip-finishmode")
+	inputZipTimeout     = doc.ElementByID("zip-timeout")
 	focusedZip          *Zip
 )
 
@@ -31,10 +36,14 @@ func init() {
 	selectZipFinishMode.AddEventListener("change", func(dom.Object) {
 		focusedZip.FinishMode = ZipFinishMode(selectZipFinishMode.Get("value").String())
 	})
+	inputZipTimeout.AddEventListener("change", durationChange(func(t time.Duration) {
+		focusedZip.Timeout = t
+	}))
 }
 
 func (z *Zip) GainFocus() {
 	focusedZip = z
 	inputZipInputNum.Set("value", z.InputNum)
 	selectZipFinishMode.Set("value", z.FinishMode)
+	inputZipTimeout.Set("value", z.Timeout.String())
 }
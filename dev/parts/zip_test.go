@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parts
+
+import (
+	"strings"
+	"testing"
+)
+
+// The feed node sends a value on input0, then one on input1 before the
+// timeout, then waits for several timeouts before sending another value
+// on input0.
+const zipLatestTimeoutGraph = `{
+	"name": "ziplatest",
+	"package_path": "ziplatest",
+	"is_command": true,
+	"nodes": {
+		"feed": {
+			"part": {
+				"imports": ["\"time\""],
+				"head": [""],
+				"body": [
+					"output0 <- 1",
+					"output1 <- 2",
+					"time.Sleep(100 * time.Millisecond)",
+					"output0 <- 3",
+					"close(output0)",
+					"close(output1)"
+				],
+				"tail": [""],
+				"pins": {
+					"output0": {"type": "int", "dir": "out"},
+					"output1": {"type": "int", "dir": "out"}
+				}
+			},
+			"part_type": "Code",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"output0": "c0", "output1": "c1"}
+		},
+		"zip": {
+			"part": {"input_num": 2, "finish_mode": "latest", "timeout": 20000000},
+			"part_type": "Zip",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"input0": "c0", "input1": "c1", "output": "c2"}
+		},
+		"print": {
+			"part": {
+				"imports": ["\"fmt\""],
+				"head": [""],
+				"body": ["for t := range input { fmt.Printf(\"%+v\\n\", t) }"],
+				"tail": [""],
+				"pins": {
+					"input": {"type": "$T", "dir": "in"}
+				}
+			},
+			"part_type": "Code",
+			"enabled": true,
+			"wait": true,
+			"multiplicity": "1",
+			"connections": {"input": "c2"}
+		}
+	},
+	"channels": {"c0": {"cap": 0}, "c1": {"cap": 0}, "c2": {"cap": 0}}
+}`
+
+func TestZipLatestTimeoutSendsEachChangeOnce(t *testing.T) {
+	got := strings.TrimSpace(goRunGraph(t, zipLatestTimeoutGraph))
+	want := "{Field0:1 Valid0:true Field1:2 Valid1:true}\n{Field0:3 Valid0:true Field1:2 Valid1:true}"
+	if got != want {
+		t.Errorf("go run output:\n%s\nwant:\n%s", got, want)
+	}
+}